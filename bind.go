@@ -0,0 +1,416 @@
+package web
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidBindTarget Bind 的参数不是非 nil 的结构体指针
+var ErrInvalidBindTarget = errors.New("bind target must be a non-nil pointer to struct")
+
+// ErrUnsupportedFieldType 字段类型不能从字符串转换
+var ErrUnsupportedFieldType = errors.New("unsupported field type")
+
+// ErrUnknownField 严格模式下请求体中有结构体不存在的字段
+var ErrUnknownField = errors.New("unknown field")
+
+// bindSources 字段可以同时声明多个来源，按这个顺序取第一个有值的
+var bindSources = []string{"path", "query", "form", "header", "cookie"}
+
+// FieldError 单个字段绑定失败的原因
+type FieldError struct {
+	// Field 结构体中的字段名，嵌套的字段用 . 连接，如 Page.Size
+	Field string
+	// Source 值的来源，path、query、form、header 或 cookie
+	Source string
+	// Name tag 中声明的参数名
+	Name  string
+	Value string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("bind %s %q to field %s: %v", e.Source, e.Name, e.Field, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// BindErrors 所有绑定失败的字段
+type BindErrors []*FieldError
+
+func (e BindErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e BindErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, fe := range e {
+		errs[i] = fe
+	}
+	return errs
+}
+
+// Bind 根据字段 tag 从路径参数、查询参数、表单、请求头和 cookie 中读取值填充 val，val 必须是结构体指针
+// 没有值的字段保持原样，可以预先设置默认值；没有 tag 的匿名结构体字段会展开绑定
+// time.Time 默认按 RFC3339 解析，可以通过 time_format tag 指定格式
+// 转换失败时继续绑定其他字段，返回的错误是 BindErrors；全部绑定成功后按 validate tag 校验，失败时返回 ValidationErrors
+// 这两种错误都会先交给 Engine.BindErrorHandler 处理，默认返回 400
+//
+//	type ListReq struct {
+//		ID    int64     `path:"id"`
+//		Page  int       `query:"page"`
+//		Tags  []string  `query:"tag"`
+//		Token string    `header:"X-Token"`
+//		Sid   *string   `cookie:"sid"`
+//		Since time.Time `query:"since" time_format:"2006-01-02"`
+//	}
+func (c *Context) Bind(val any) error {
+	rv := reflect.ValueOf(val)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return ErrInvalidBindTarget
+	}
+	fields := cachedBindFields(rv.Elem().Type())
+	if err := c.parseBindForm(fields); err != nil {
+		return err
+	}
+
+	var errs BindErrors
+	for _, f := range fields {
+		source, name, vals := c.lookupBindValues(f)
+		if len(vals) == 0 {
+			continue
+		}
+		if err := setField(rv.Elem(), f, vals); err != nil {
+			errs = append(errs, &FieldError{
+				Field:  f.name,
+				Source: source,
+				Name:   name,
+				Value:  vals[0],
+				Err:    err,
+			})
+		}
+	}
+	if len(errs) > 0 {
+		return c.handleBindError(errs)
+	}
+	if err := Validate(val); err != nil {
+		return c.handleBindError(err)
+	}
+	return nil
+}
+
+// DefaultBindErrorHandler 返回 400，响应体列出每个出错的字段，如
+// {"message":"invalid request","errors":[{"field":"Page","rule":"min","param":"1","message":"field Page failed on rule min=1"}]}
+var DefaultBindErrorHandler = func(ctx *Context, err error) {
+	type fieldError struct {
+		Field   string `json:"field"`
+		Source  string `json:"source,omitempty"`
+		Rule    string `json:"rule,omitempty"`
+		Param   string `json:"param,omitempty"`
+		Message string `json:"message"`
+	}
+	res := struct {
+		Message string       `json:"message"`
+		Errors  []fieldError `json:"errors"`
+	}{Message: "invalid request"}
+
+	var bindErrs BindErrors
+	var validationErrs ValidationErrors
+	switch {
+	case errors.As(err, &bindErrs):
+		for _, fe := range bindErrs {
+			res.Errors = append(res.Errors, fieldError{Field: fe.Field, Source: fe.Source, Message: fe.Error()})
+		}
+	case errors.As(err, &validationErrs):
+		for _, ve := range validationErrs {
+			res.Errors = append(res.Errors, fieldError{Field: ve.Field, Rule: ve.Rule, Param: ve.Param, Message: ve.Error()})
+		}
+	}
+	_ = ctx.JSON(http.StatusBadRequest, res)
+}
+
+// handleBindError 交给 Engine.BindErrorHandler 输出响应，返回原来的错误
+func (c *Context) handleBindError(err error) error {
+	handler := DefaultBindErrorHandler
+	if c.engine != nil {
+		handler = c.engine.BindErrorHandler
+	}
+	if handler != nil {
+		handler(c, err)
+	}
+	return err
+}
+
+// parseBindForm 有字段声明 form tag 时才解析请求体
+func (c *Context) parseBindForm(fields []bindField) error {
+	for _, f := range fields {
+		if f.tags["form"] == "" {
+			continue
+		}
+		ct, _, _ := mime.ParseMediaType(c.Req.Header.Get("Content-Type"))
+		if ct == "multipart/form-data" {
+			return c.Req.ParseMultipartForm(32 << 20)
+		}
+		return c.Req.ParseForm()
+	}
+	return nil
+}
+
+func (c *Context) lookupBindValues(f bindField) (string, string, []string) {
+	for _, source := range bindSources {
+		name := f.tags[source]
+		if name == "" {
+			continue
+		}
+		var vals []string
+		switch source {
+		case "path":
+			if val, ok := c.PathParams.Get(name); ok {
+				vals = []string{val}
+			}
+		case "query":
+			if c.queryCache == nil {
+				c.queryCache = c.Req.URL.Query()
+			}
+			vals = c.queryCache[name]
+		case "form":
+			vals = c.Req.Form[name]
+		case "header":
+			vals = c.Req.Header.Values(name)
+		case "cookie":
+			for _, cookie := range c.Req.Cookies() {
+				if cookie.Name == name {
+					vals = append(vals, cookie.Value)
+				}
+			}
+		}
+		if len(vals) > 0 {
+			return source, name, vals
+		}
+	}
+	return "", "", nil
+}
+
+type bindField struct {
+	// index 字段在结构体中的位置，嵌入的结构体有多层
+	index []int
+	name  string
+	// tags 来源到参数名的映射
+	tags       map[string]string
+	timeFormat string
+}
+
+var bindFieldsCache sync.Map
+
+func cachedBindFields(typ reflect.Type) []bindField {
+	if fields, ok := bindFieldsCache.Load(typ); ok {
+		return fields.([]bindField)
+	}
+	fields := parseBindFields(typ, nil, "")
+	bindFieldsCache.Store(typ, fields)
+	return fields
+}
+
+func parseBindFields(typ reflect.Type, index []int, prefix string) []bindField {
+	var res []bindField
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		f := bindField{
+			index:      append(append([]int(nil), index...), i),
+			name:       prefix + sf.Name,
+			tags:       make(map[string]string, 1),
+			timeFormat: sf.Tag.Get("time_format"),
+		}
+		for _, source := range bindSources {
+			if name := sf.Tag.Get(source); name != "" && name != "-" {
+				f.tags[source] = name
+			}
+		}
+		if len(f.tags) > 0 {
+			if sf.IsExported() {
+				res = append(res, f)
+			}
+			continue
+		}
+		ft := sf.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && ft.Kind() == reflect.Struct && ft != timeType {
+			res = append(res, parseBindFields(ft, f.index, f.name+".")...)
+		}
+	}
+	return res
+}
+
+func setField(v reflect.Value, f bindField, vals []string) error {
+	fv, err := fieldByIndex(v, f.index)
+	if err != nil {
+		return err
+	}
+	return setValue(fv, vals, f.timeFormat)
+}
+
+// fieldByIndex 按 index 找到字段，途经 nil 的嵌入结构体指针时分配
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, idx := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, ErrUnsupportedFieldType
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(idx)
+	}
+	return v, nil
+}
+
+// bindValues 只使用 source 对应的 tag 从 values 中绑定，strict 为 true 时 values 中有没有对应字段的 key 返回 ErrUnknownField
+func bindValues(v reflect.Value, source string, values map[string][]string, strict bool) error {
+	fields := cachedBindFields(v.Type())
+	var errs BindErrors
+	for _, f := range fields {
+		name := f.tags[source]
+		vals := values[name]
+		if name == "" || len(vals) == 0 {
+			continue
+		}
+		if err := setField(v, f, vals); err != nil {
+			errs = append(errs, &FieldError{
+				Field:  f.name,
+				Source: source,
+				Name:   name,
+				Value:  vals[0],
+				Err:    err,
+			})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	if strict {
+		return checkUnknownKeys(fields, source, values)
+	}
+	return nil
+}
+
+func checkUnknownKeys[V any](fields []bindField, source string, values map[string]V) error {
+	for key := range values {
+		known := false
+		for _, f := range fields {
+			if f.tags[source] == key {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("%w: %s", ErrUnknownField, key)
+		}
+	}
+	return nil
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	durationType      = reflect.TypeOf(time.Duration(0))
+	textUnmarshalType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+func setValue(v reflect.Value, vals []string, timeFormat string) error {
+	if v.Kind() == reflect.Pointer {
+		elem := reflect.New(v.Type().Elem())
+		if err := setValue(elem.Elem(), vals, timeFormat); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 && !reflect.PointerTo(v.Type()).Implements(textUnmarshalType) {
+		slice := reflect.MakeSlice(v.Type(), len(vals), len(vals))
+		for i, val := range vals {
+			if err := setValue(slice.Index(i), []string{val}, timeFormat); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+	return setString(v, vals[0], timeFormat)
+}
+
+func setString(v reflect.Value, val string, timeFormat string) error {
+	switch v.Type() {
+	case timeType:
+		if timeFormat == "" {
+			timeFormat = time.RFC3339
+		}
+		t, err := time.Parse(timeFormat, val)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case durationType:
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(val))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(val)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(val, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(val, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(val, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		// []byte
+		v.SetBytes([]byte(val))
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedFieldType, v.Type())
+	}
+	return nil
+}
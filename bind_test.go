@@ -0,0 +1,161 @@
+package web
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type Pagination struct {
+	Page int `query:"page"`
+	Size int `query:"size"`
+}
+
+type Filter struct {
+	Tags  []string  `query:"tag" form:"tag"`
+	Since time.Time `query:"since" time_format:"2006-01-02"`
+}
+
+type bindReq struct {
+	Pagination
+	*Filter
+	ID      int64          `path:"id"`
+	Name    string         `form:"name" query:"name"`
+	Token   string         `header:"X-Token"`
+	Session *string        `cookie:"sid"`
+	Debug   bool           `query:"debug"`
+	Ratio   float32        `query:"ratio"`
+	Timeout time.Duration  `query:"timeout"`
+	IP      net.IP         `header:"X-Real-IP"`
+	Scores  []uint16       `query:"score"`
+	Ignored string         `query:"-"`
+	hidden  string         `query:"hidden"`
+	Extra   map[string]int `query:"extra"`
+}
+
+func TestContext_Bind(t *testing.T) {
+	sid := "s-1"
+	testCases := []struct {
+		name    string
+		req     func() *http.Request
+		params  Params
+		want    bindReq
+		wantErr []string
+	}{
+		{
+			name: "all sources",
+			req: func() *http.Request {
+				q := "page=2&tag=a&tag=b&since=2024-05-01&debug=true&ratio=0.5&timeout=3s&score=1&score=2&Ignored=x&hidden=x"
+				req := httptest.NewRequest(http.MethodPost, "/users/42?"+q, strings.NewReader("name=tom"))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				req.Header.Set("X-Token", "abc")
+				req.Header.Set("X-Real-IP", "10.0.0.1")
+				req.AddCookie(&http.Cookie{Name: "sid", Value: sid})
+				return req
+			},
+			params: Params{{Key: "id", Value: "42"}},
+			want: bindReq{
+				Pagination: Pagination{Page: 2, Size: 10},
+				Filter: &Filter{
+					Tags:  []string{"a", "b"},
+					Since: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+				},
+				ID:      42,
+				Name:    "tom",
+				Token:   "abc",
+				Session: &sid,
+				Debug:   true,
+				Ratio:   0.5,
+				Timeout: 3 * time.Second,
+				IP:      net.ParseIP("10.0.0.1"),
+				Scores:  []uint16{1, 2},
+			},
+		},
+		{
+			name: "multipart form",
+			req: func() *http.Request {
+				body := &bytes.Buffer{}
+				w := multipart.NewWriter(body)
+				_ = w.WriteField("name", "jerry")
+				_ = w.WriteField("tag", "x")
+				_ = w.Close()
+				req := httptest.NewRequest(http.MethodPost, "/users", body)
+				req.Header.Set("Content-Type", w.FormDataContentType())
+				return req
+			},
+			want: bindReq{
+				Pagination: Pagination{Size: 10},
+				Filter:     &Filter{Tags: []string{"x"}},
+				Name:       "jerry",
+			},
+		},
+		{
+			name: "field errors",
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/users/abc?page=x&debug=yes&score=70000&name=tom", nil)
+			},
+			params: Params{{Key: "id", Value: "abc"}},
+			want: bindReq{
+				Pagination: Pagination{Size: 10},
+				Name:       "tom",
+			},
+			wantErr: []string{"Pagination.Page", "ID", "Debug", "Scores"},
+		},
+		{
+			name: "unsupported type",
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/users?extra=1", nil)
+			},
+			want:    bindReq{Pagination: Pagination{Size: 10}},
+			wantErr: []string{"Extra"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := newContext(httptest.NewRecorder(), tc.req())
+			ctx.PathParams = tc.params
+			// 没有值的字段保留默认值
+			req := bindReq{Pagination: Pagination{Size: 10}}
+			err := ctx.Bind(&req)
+			assert.Equal(t, tc.want, req)
+			if len(tc.wantErr) == 0 {
+				require.NoError(t, err)
+				return
+			}
+			var errs BindErrors
+			require.ErrorAs(t, err, &errs)
+			fields := make([]string, len(errs))
+			for i, fe := range errs {
+				fields[i] = fe.Field
+			}
+			assert.Equal(t, tc.wantErr, fields)
+		})
+	}
+}
+
+func TestContext_Bind_fieldError(t *testing.T) {
+	ctx := newContext(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/?page="+url.QueryEscape("1.5"), nil))
+	var req Pagination
+	err := ctx.Bind(&req)
+	var errs BindErrors
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 1)
+	assert.Equal(t, "query", errs[0].Source)
+	assert.Equal(t, "page", errs[0].Name)
+	assert.Equal(t, "1.5", errs[0].Value)
+	assert.ErrorIs(t, err, strconv.ErrSyntax)
+	assert.Equal(t, `bind query "page" to field Page: strconv.ParseInt: parsing "1.5": invalid syntax`, err.Error())
+
+	assert.ErrorIs(t, ctx.Bind(req), ErrInvalidBindTarget)
+	assert.ErrorIs(t, ctx.Bind((*Pagination)(nil)), ErrInvalidBindTarget)
+}
@@ -0,0 +1,261 @@
+package web
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/KNICEX/go-web/msgpack"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+)
+
+// ErrUnsupportedMediaType 没有能解码请求体 Content-Type 的 Decoder
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+// Decoder 把某种格式的请求体解码到 val
+type Decoder interface {
+	// MediaTypes 能解码的 Content-Type，不包含参数
+	MediaTypes() []string
+	// Decode 从 req.Body 读取请求体，strict 为 true 时请求体中有 val 不存在的字段返回错误
+	Decode(req *http.Request, val any, strict bool) error
+}
+
+var decoders = []Decoder{
+	JSONDecoder{},
+	XMLDecoder{},
+	FormDecoder{},
+	MultipartDecoder{},
+	YAMLDecoder{},
+	MsgPackDecoder{},
+	ProtobufDecoder{},
+}
+
+// RegisterDecoder 注册 Decoder，第一个媒体类型和已有的 Decoder 相同时替换已有的，需要在处理请求之前调用
+func RegisterDecoder(d Decoder) {
+	for i, old := range decoders {
+		if old.MediaTypes()[0] == d.MediaTypes()[0] {
+			decoders[i] = d
+			return
+		}
+	}
+	decoders = append(decoders, d)
+}
+
+func lookupDecoder(mediaType string) (Decoder, bool) {
+	for _, d := range decoders {
+		for _, t := range d.MediaTypes() {
+			if t == mediaType {
+				return d, true
+			}
+		}
+	}
+	return nil, false
+}
+
+type JSONDecoder struct{}
+
+func (JSONDecoder) MediaTypes() []string {
+	return []string{"application/json"}
+}
+
+func (JSONDecoder) Decode(req *http.Request, val any, strict bool) error {
+	dec := json.NewDecoder(req.Body)
+	if strict {
+		dec.DisallowUnknownFields()
+	}
+	return dec.Decode(val)
+}
+
+// XMLDecoder encoding/xml 不能检查多余的字段，忽略 strict
+type XMLDecoder struct{}
+
+func (XMLDecoder) MediaTypes() []string {
+	return []string{"application/xml", "text/xml"}
+}
+
+func (XMLDecoder) Decode(req *http.Request, val any, _ bool) error {
+	return xml.NewDecoder(req.Body).Decode(val)
+}
+
+// FormDecoder 按 form tag 绑定，规则和 Context.Bind 相同，只读取请求体中的参数
+type FormDecoder struct{}
+
+func (FormDecoder) MediaTypes() []string {
+	return []string{"application/x-www-form-urlencoded"}
+}
+
+func (FormDecoder) Decode(req *http.Request, val any, strict bool) error {
+	rv, err := bindTarget(val)
+	if err != nil {
+		return err
+	}
+	if err = req.ParseForm(); err != nil {
+		return err
+	}
+	return bindValues(rv, "form", req.PostForm, strict)
+}
+
+// MultipartDecoder 按 form tag 绑定，文件可以绑定到 *multipart.FileHeader 或 []*multipart.FileHeader 类型的字段
+type MultipartDecoder struct{}
+
+func (MultipartDecoder) MediaTypes() []string {
+	return []string{"multipart/form-data"}
+}
+
+var (
+	fileHeaderType  = reflect.TypeOf((*multipart.FileHeader)(nil))
+	fileHeadersType = reflect.TypeOf([]*multipart.FileHeader(nil))
+)
+
+func (MultipartDecoder) Decode(req *http.Request, val any, strict bool) error {
+	rv, err := bindTarget(val)
+	if err != nil {
+		return err
+	}
+	if err = req.ParseMultipartForm(32 << 20); err != nil {
+		return err
+	}
+	form := req.MultipartForm
+	if err = bindValues(rv, "form", form.Value, strict); err != nil {
+		return err
+	}
+	fields := cachedBindFields(rv.Type())
+	for _, f := range fields {
+		files := form.File[f.tags["form"]]
+		if len(files) == 0 {
+			continue
+		}
+		fv, err := fieldByIndex(rv, f.index)
+		if err != nil {
+			return err
+		}
+		switch fv.Type() {
+		case fileHeaderType:
+			fv.Set(reflect.ValueOf(files[0]))
+		case fileHeadersType:
+			fv.Set(reflect.ValueOf(files))
+		}
+	}
+	if strict {
+		return checkUnknownKeys(fields, "form", form.File)
+	}
+	return nil
+}
+
+type YAMLDecoder struct{}
+
+func (YAMLDecoder) MediaTypes() []string {
+	return []string{"application/yaml", "application/x-yaml", "text/yaml"}
+}
+
+func (YAMLDecoder) Decode(req *http.Request, val any, strict bool) error {
+	dec := yaml.NewDecoder(req.Body)
+	dec.KnownFields(strict)
+	return dec.Decode(val)
+}
+
+// MsgPackDecoder 字段名规则和 MsgPackRenderer 相同
+type MsgPackDecoder struct{}
+
+func (MsgPackDecoder) MediaTypes() []string {
+	return []string{"application/msgpack", "application/x-msgpack"}
+}
+
+func (MsgPackDecoder) Decode(req *http.Request, val any, strict bool) error {
+	data, err := io.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	if !strict {
+		return msgpack.Unmarshal(data, val)
+	}
+	if err = msgpack.UnmarshalStrict(data, val); errors.Is(err, msgpack.ErrUnknownField) {
+		return fmt.Errorf("%w: %w", ErrUnknownField, err)
+	}
+	return err
+}
+
+// ProtobufDecoder val 必须是 proto.Message，strict 只检查最外层消息中的未知字段
+type ProtobufDecoder struct{}
+
+func (ProtobufDecoder) MediaTypes() []string {
+	return []string{"application/x-protobuf", "application/protobuf"}
+}
+
+func (ProtobufDecoder) Decode(req *http.Request, val any, strict bool) error {
+	msg, ok := val.(proto.Message)
+	if !ok {
+		return fmt.Errorf("protobuf decode: %T is not a proto.Message", val)
+	}
+	data, err := io.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	if err = proto.Unmarshal(data, msg); err != nil {
+		return err
+	}
+	if strict && len(msg.ProtoReflect().GetUnknown()) > 0 {
+		return fmt.Errorf("%w in protobuf message", ErrUnknownField)
+	}
+	return nil
+}
+
+func bindTarget(val any) (reflect.Value, error) {
+	rv := reflect.ValueOf(val)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, ErrInvalidBindTarget
+	}
+	return rv.Elem(), nil
+}
+
+// BindBody 根据 Content-Type 选择 Decoder 解码请求体，然后按 validate tag 校验
+// 没有对应的 Decoder 时返回 415，请求体超过 Engine.MaxBodySize 时返回 413，请求体格式错误时返回 400
+// 字段绑定失败和校验失败时交给 Engine.BindErrorHandler 处理，响应都可以在之后覆盖
+func (c *Context) BindBody(val any) error {
+	mediaType, _, err := mime.ParseMediaType(c.Req.Header.Get("Content-Type"))
+	d, ok := lookupDecoder(mediaType)
+	if err != nil || !ok {
+		c.StatusCode = http.StatusUnsupportedMediaType
+		c.RespData = []byte("415 unsupported media type")
+		return fmt.Errorf("%w: %q", ErrUnsupportedMediaType, c.Req.Header.Get("Content-Type"))
+	}
+	return c.decodeBody(d, val)
+}
+
+func (c *Context) decodeBody(d Decoder, val any) error {
+	var maxSize int64
+	strict := false
+	if c.engine != nil {
+		maxSize = c.engine.MaxBodySize
+		strict = c.engine.StrictBody
+	}
+	if maxSize > 0 {
+		c.Req.Body = http.MaxBytesReader(c.Resp, c.Req.Body, maxSize)
+	}
+
+	if err := d.Decode(c.Req, val, strict); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		var bindErrs BindErrors
+		switch {
+		case errors.As(err, &maxBytesErr):
+			c.StatusCode = http.StatusRequestEntityTooLarge
+			c.RespData = []byte("413 request entity too large")
+		case errors.As(err, &bindErrs):
+			return c.handleBindError(err)
+		default:
+			c.StatusCode = http.StatusBadRequest
+			c.RespData = []byte("400 bad request")
+		}
+		return err
+	}
+	if err := Validate(val); err != nil {
+		return c.handleBindError(err)
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+package web
+
+import (
+	"bytes"
+	"github.com/KNICEX/go-web/msgpack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type bodyReq struct {
+	Name string   `json:"name" xml:"name" yaml:"name" form:"name" msgpack:"name" validate:"required"`
+	Age  int      `json:"age" xml:"age" yaml:"age" form:"age" msgpack:"age"`
+	Tags []string `json:"tags" xml:"tag" yaml:"tags" form:"tag" msgpack:"tags"`
+}
+
+func TestContext_BindBody(t *testing.T) {
+	msgpackBody, err := msgpack.Marshal(map[string]any{"name": "tom", "age": 18, "tags": []string{"a", "b"}})
+	require.NoError(t, err)
+	msgpackUnknown, err := msgpack.Marshal(map[string]any{"name": "tom", "email": "x"})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name        string
+		opts        []EngineOption
+		contentType string
+		body        string
+		wantStatus  int
+		wantBody    string
+	}{
+		{
+			name:        "json",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"name":"tom","age":18,"tags":["a","b"]}`,
+			wantStatus:  http.StatusOK,
+			wantBody:    "tom 18 [a b]",
+		},
+		{
+			name:        "xml",
+			contentType: "text/xml",
+			body:        `<bodyReq><name>tom</name><age>18</age><tag>a</tag><tag>b</tag></bodyReq>`,
+			wantStatus:  http.StatusOK,
+			wantBody:    "tom 18 [a b]",
+		},
+		{
+			name:        "form",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "name=tom&age=18&tag=a&tag=b",
+			wantStatus:  http.StatusOK,
+			wantBody:    "tom 18 [a b]",
+		},
+		{
+			name:        "yaml",
+			contentType: "application/yaml",
+			body:        "name: tom\nage: 18\ntags: [a, b]\n",
+			wantStatus:  http.StatusOK,
+			wantBody:    "tom 18 [a b]",
+		},
+		{
+			name:        "msgpack",
+			contentType: "application/msgpack",
+			body:        string(msgpackBody),
+			wantStatus:  http.StatusOK,
+			wantBody:    "tom 18 [a b]",
+		},
+		{
+			name:        "unknown fields ignored",
+			contentType: "application/json",
+			body:        `{"name":"tom","email":"x"}`,
+			wantStatus:  http.StatusOK,
+			wantBody:    "tom 0 []",
+		},
+		{
+			name:        "strict json",
+			opts:        []EngineOption{WithStrictBody(true)},
+			contentType: "application/json",
+			body:        `{"name":"tom","email":"x"}`,
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    "400 bad request",
+		},
+		{
+			name:        "strict yaml",
+			opts:        []EngineOption{WithStrictBody(true)},
+			contentType: "application/yaml",
+			body:        "name: tom\nemail: x\n",
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    "400 bad request",
+		},
+		{
+			name:        "strict form",
+			opts:        []EngineOption{WithStrictBody(true)},
+			contentType: "application/x-www-form-urlencoded",
+			body:        "name=tom&email=x",
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    "400 bad request",
+		},
+		{
+			name:        "strict msgpack",
+			opts:        []EngineOption{WithStrictBody(true)},
+			contentType: "application/msgpack",
+			body:        string(msgpackUnknown),
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    "400 bad request",
+		},
+		{
+			name:        "deeply nested msgpack",
+			contentType: "application/msgpack",
+			body:        strings.Repeat("\x91", 20<<20) + "\xc0",
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    "400 bad request",
+		},
+		{
+			name:        "unsupported media type",
+			contentType: "text/csv",
+			body:        "tom,18",
+			wantStatus:  http.StatusUnsupportedMediaType,
+			wantBody:    "415 unsupported media type",
+		},
+		{
+			name:       "missing content type",
+			body:       `{"name":"tom"}`,
+			wantStatus: http.StatusUnsupportedMediaType,
+			wantBody:   "415 unsupported media type",
+		},
+		{
+			name:        "too large",
+			opts:        []EngineOption{WithMaxBodySize(16)},
+			contentType: "application/json",
+			body:        `{"name":"tom","age":18}`,
+			wantStatus:  http.StatusRequestEntityTooLarge,
+			wantBody:    "413 request entity too large",
+		},
+		{
+			name:        "form too large",
+			opts:        []EngineOption{WithMaxBodySize(8)},
+			contentType: "application/x-www-form-urlencoded",
+			body:        "name=tom&age=18",
+			wantStatus:  http.StatusRequestEntityTooLarge,
+			wantBody:    "413 request entity too large",
+		},
+		{
+			name:        "syntax error",
+			contentType: "application/json",
+			body:        `{"name":`,
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    "400 bad request",
+		},
+		{
+			name:        "form field error",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "name=tom&age=x",
+			wantStatus:  http.StatusBadRequest,
+			wantBody: `{"message":"invalid request","errors":[{"field":"Age","source":"form",` +
+				`"message":"bind form \"age\" to field Age: strconv.ParseInt: parsing \"x\": invalid syntax"}]}`,
+		},
+		{
+			name:        "validation",
+			contentType: "application/json",
+			body:        `{"age":18}`,
+			wantStatus:  http.StatusBadRequest,
+			wantBody: `{"message":"invalid request","errors":[{"field":"Name","rule":"required",` +
+				`"message":"field Name failed on rule required"}]}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := NewEngine(tc.opts...)
+			e.POST("/users", func(ctx *Context) {
+				var req bodyReq
+				if err := ctx.BindBody(&req); err != nil {
+					return
+				}
+				_ = ctx.String(http.StatusOK, req.Name+" "+strconv.Itoa(req.Age)+" ["+strings.Join(req.Tags, " ")+"]")
+			})
+			req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(tc.body))
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			recorder := httptest.NewRecorder()
+			e.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantStatus, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+		})
+	}
+}
+
+func TestMultipartDecoder(t *testing.T) {
+	type uploadReq struct {
+		Title  string                  `form:"title"`
+		Avatar *multipart.FileHeader   `form:"avatar"`
+		Photos []*multipart.FileHeader `form:"photo"`
+	}
+	newRequest := func(extra string) *http.Request {
+		body := &bytes.Buffer{}
+		w := multipart.NewWriter(body)
+		_ = w.WriteField("title", "album")
+		fw, _ := w.CreateFormFile("avatar", "a.png")
+		_, _ = fw.Write([]byte("avatar"))
+		for _, name := range []string{"1.png", "2.png"} {
+			fw, _ = w.CreateFormFile("photo", name)
+			_, _ = fw.Write([]byte(name))
+		}
+		if extra != "" {
+			fw, _ = w.CreateFormFile(extra, "x.png")
+			_, _ = fw.Write([]byte("x"))
+		}
+		_ = w.Close()
+		req := httptest.NewRequest(http.MethodPost, "/upload", body)
+		req.Header.Set("Content-Type", w.FormDataContentType())
+		return req
+	}
+
+	ctx := newContext(httptest.NewRecorder(), newRequest(""))
+	var req uploadReq
+	require.NoError(t, ctx.BindBody(&req))
+	assert.Equal(t, "album", req.Title)
+	require.NotNil(t, req.Avatar)
+	assert.Equal(t, "a.png", req.Avatar.Filename)
+	f, err := req.Avatar.Open()
+	require.NoError(t, err)
+	data, _ := io.ReadAll(f)
+	assert.Equal(t, "avatar", string(data))
+	require.Len(t, req.Photos, 2)
+	assert.Equal(t, "2.png", req.Photos[1].Filename)
+
+	e := NewEngine(WithStrictBody(true))
+	ctx = newContext(httptest.NewRecorder(), newRequest("cover"))
+	ctx.engine = e
+	assert.ErrorIs(t, ctx.BindBody(&uploadReq{}), ErrUnknownField)
+}
+
+func TestProtobufDecoder(t *testing.T) {
+	// 字段 1 是 value，字段 2 是未知字段
+	body := []byte{0x0a, 0x02, 'h', 'i', 0x10, 0x01}
+	newContextWith := func(strict bool) *Context {
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-protobuf")
+		ctx := newContext(httptest.NewRecorder(), req)
+		ctx.engine = NewEngine(WithStrictBody(strict))
+		return ctx
+	}
+
+	msg := &wrapperspb.StringValue{}
+	require.NoError(t, newContextWith(false).BindBody(msg))
+	assert.Equal(t, "hi", msg.Value)
+
+	ctx := newContextWith(true)
+	assert.ErrorIs(t, ctx.BindBody(&wrapperspb.StringValue{}), ErrUnknownField)
+	assert.Equal(t, http.StatusBadRequest, ctx.StatusCode)
+
+	assert.Error(t, newContextWith(false).BindBody(&bodyReq{}))
+}
@@ -3,35 +3,84 @@ package web
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"maps"
 	"math"
 	"mime/multipart"
 	"net/http"
 	"net/url"
+	"slices"
+	"strconv"
 )
 
 const abortIndex int = math.MaxInt8
 
+var ErrPathParamNotFound = errors.New("path param not found")
+
+// Context 只在处理函数执行期间有效，Engine 会复用 Context，处理函数返回后不能再持有它
 type Context struct {
 	Req          *http.Request
 	Resp         http.ResponseWriter
-	PathParams   map[string]string
+	PathParams   Params
 	queryCache   url.Values
 	MatchedRoute string
 	Values       map[string]any
+	// 匹配到的路由的元数据
+	routeMeta map[any]any
+	// 处理请求的 Engine，直接通过 newContext 创建时为 nil
+	engine *Engine
 
 	index    int
 	handlers []HandleFunc
+	// Context.Error 记录的错误
+	errs []error
 
 	StatusCode int
 	RespData   []byte
+	// Resp 默认指向 writer，用于记录响应是否已经提交
+	writer responseWriter
 }
 
 func newContext(w http.ResponseWriter, req *http.Request) *Context {
-	return &Context{
+	c := &Context{
 		Req:   req,
-		Resp:  w,
 		index: -1,
 	}
+	c.writer.reset(w, c)
+	c.Resp = &c.writer
+	return c
+}
+
+// reset 重置从 sync.Pool 中取出的 Context，保留 PathParams 和 Values 已经分配的空间
+func (c *Context) reset(e *Engine, w http.ResponseWriter, req *http.Request) {
+	params := c.PathParams[:0]
+	if maxParams := e.load().maxParams; cap(params) < maxParams {
+		params = make(Params, 0, maxParams)
+	}
+	values := c.Values
+	clear(values)
+	*c = Context{
+		Req:        req,
+		PathParams: params,
+		Values:     values,
+		engine:     e,
+		index:      -1,
+	}
+	c.writer.reset(w, c)
+	c.Resp = &c.writer
+}
+
+// Copy 返回可以在处理函数返回后继续使用的副本，如传给其他 goroutine
+// 副本只用于读取请求相关的数据，不能用于写响应
+func (c *Context) Copy() *Context {
+	return &Context{
+		Req:          c.Req,
+		PathParams:   slices.Clone(c.PathParams),
+		MatchedRoute: c.MatchedRoute,
+		Values:       maps.Clone(c.Values),
+		routeMeta:    c.routeMeta,
+		index:        abortIndex,
+	}
 }
 
 func (c *Context) Get(key string) (any, bool) {
@@ -94,15 +143,17 @@ func (c *Context) GetCookie(name string) (*http.Cookie, bool) {
 	return cookie, true
 }
 
+// BindJSON 不检查 Content-Type，按 JSON 解码请求体，其余和 BindBody 相同
 func (c *Context) BindJSON(val any) error {
 	if val == nil {
 		return errors.New("nil pointer")
 	}
-	return json.NewDecoder(c.Req.Body).Decode(val)
+	return c.decodeBody(JSONDecoder{}, val)
 }
 
 func (c *Context) Param(key string) string {
-	return c.PathParams[key]
+	val, _ := c.PathParams.Get(key)
+	return val
 }
 
 func (c *Context) FormValue(key string) (string, bool) {
@@ -137,10 +188,74 @@ func (c *Context) QueryValue(key string) (string, bool) {
 }
 
 func (c *Context) PathValue(key string) (string, bool) {
-	res, ok := c.PathParams[key]
+	res, ok := c.PathParams.Get(key)
 	return res, ok
 }
 
+func (c *Context) pathValue(key string) (string, error) {
+	val, ok := c.PathParams.Get(key)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrPathParamNotFound, key)
+	}
+	return val, nil
+}
+
+func (c *Context) ParamInt(key string) (int, error) {
+	val, err := c.pathValue(key)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(val)
+}
+
+func (c *Context) ParamInt64(key string) (int64, error) {
+	val, err := c.pathValue(key)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(val, 10, 64)
+}
+
+func (c *Context) ParamUint64(key string) (uint64, error) {
+	val, err := c.pathValue(key)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(val, 10, 64)
+}
+
+func (c *Context) ParamFloat64(key string) (float64, error) {
+	val, err := c.pathValue(key)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(val, 64)
+}
+
+func (c *Context) ParamBool(key string) (bool, error) {
+	val, err := c.pathValue(key)
+	if err != nil {
+		return false, err
+	}
+	return strconv.ParseBool(val)
+}
+
+// Meta 返回匹配到的路由通过 IRouterGroup.WithMeta 或 IRouterGroup.Meta 设置的元数据
+func (c *Context) Meta(key any) (any, bool) {
+	val, ok := c.routeMeta[key]
+	return val, ok
+}
+
+// MetaValue 返回类型为 T 的路由元数据，不存在或类型不匹配时返回 false
+//
+//	type scopesKey struct{}
+//	e.WithMeta(scopesKey{}, []string{"admin"}).GET("/admin", handler)
+//	scopes, ok := web.MetaValue[[]string](ctx, scopesKey{})
+func MetaValue[T any](c *Context, key any) (T, bool) {
+	val, ok := c.routeMeta[key].(T)
+	return val, ok
+}
+
 func (c *Context) Next() {
 	c.index++
 	for n := len(c.handlers); c.index < n; c.index++ {
@@ -0,0 +1,89 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestContext_ParamInt(t *testing.T) {
+	ctx := newContext(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	ctx.PathParams = Params{
+		{Key: "id", Value: "42"},
+		{Key: "name", Value: "tom"},
+	}
+
+	id, err := ctx.ParamInt("id")
+	require.NoError(t, err)
+	assert.Equal(t, 42, id)
+
+	_, err = ctx.ParamInt("name")
+	assert.ErrorIs(t, err, strconv.ErrSyntax)
+
+	_, err = ctx.ParamInt("age")
+	assert.ErrorIs(t, err, ErrPathParamNotFound)
+}
+
+type scopesKey struct{}
+
+type tierKey struct{}
+
+func TestContext_Meta(t *testing.T) {
+	e := NewEngine()
+	e.Use(func(ctx *Context) {
+		scopes, _ := MetaValue[[]string](ctx, scopesKey{})
+		tier, ok := ctx.Meta(tierKey{})
+		if !ok {
+			tier = "default"
+		}
+		ctx.Resp.Header().Set("X-Scopes", strings.Join(scopes, ","))
+		ctx.Resp.Header().Set("X-Tier", tier.(string))
+		ctx.Next()
+	})
+	e.GET("/admin", userHandler).Meta(scopesKey{}, []string{"admin", "audit"}).Meta(tierKey{}, "gold")
+	e.GET("/public", userHandler)
+	e.Mount("/debug", http.NotFoundHandler()).Meta(tierKey{}, "internal")
+	v2 := e.Group("/v2").WithMeta(tierKey{}, "silver")
+	v2.GET("/orders", userHandler)
+	v2.WithMeta(scopesKey{}, []string{"files"}).Mount("/files", http.NotFoundHandler())
+	e.Group("/v2").GET("/plain", userHandler)
+	// 替换处理链时保留元数据
+	e.Replace(http.MethodGet, "/admin", userHandler)
+
+	testCases := []struct {
+		method     string
+		path       string
+		wantScopes string
+		wantTier   string
+	}{
+		{method: http.MethodGet, path: "/admin", wantScopes: "admin,audit", wantTier: "gold"},
+		{method: http.MethodGet, path: "/public", wantTier: "default"},
+		{method: http.MethodPost, path: "/debug/vars", wantTier: "internal"},
+		{method: http.MethodGet, path: "/v2/orders", wantTier: "silver"},
+		{method: http.MethodPut, path: "/v2/files/a", wantScopes: "files", wantTier: "silver"},
+		{method: http.MethodGet, path: "/v2/plain", wantTier: "default"},
+		// 未匹配的请求不经过中间件
+		{method: http.MethodGet, path: "/missing"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.method+tc.path, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			e.ServeHTTP(recorder, httptest.NewRequest(tc.method, tc.path, nil))
+			assert.Equal(t, tc.wantScopes, recorder.Header().Get("X-Scopes"))
+			assert.Equal(t, tc.wantTier, recorder.Header().Get("X-Tier"))
+		})
+	}
+
+	ctx := newContext(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	ctx.routeMeta = map[any]any{tierKey{}: "gold"}
+	_, ok := MetaValue[int](ctx, tierKey{})
+	assert.False(t, ok)
+
+	assert.Panics(t, func() {
+		e.Group("/v1").Meta(tierKey{}, "gold")
+	}, "no route to set meta")
+}
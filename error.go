@@ -0,0 +1,167 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+)
+
+// HTTPError 带状态码的错误，Message 会输出给客户端，Internal 只用于日志，不会出现在响应中
+//
+//	ctx.Error(web.NewHTTPError(http.StatusForbidden, "no permission").WithInternal(err))
+type HTTPError struct {
+	Code    int
+	Message string
+	// Internal 导致错误的原因
+	Internal error
+}
+
+// NewHTTPError message 为空时使用状态码对应的描述
+func NewHTTPError(code int, message string) *HTTPError {
+	if message == "" {
+		message = http.StatusText(code)
+	}
+	return &HTTPError{Code: code, Message: message}
+}
+
+func (e *HTTPError) Error() string {
+	if e.Internal != nil {
+		return fmt.Sprintf("%d %s: %v", e.Code, e.Message, e.Internal)
+	}
+	return fmt.Sprintf("%d %s", e.Code, e.Message)
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Internal
+}
+
+// WithInternal 返回设置了 Internal 的副本，e 可以是包级别的变量
+func (e *HTTPError) WithInternal(err error) *HTTPError {
+	res := *e
+	res.Internal = err
+	return &res
+}
+
+// Error 记录处理过程中的错误，err 为 nil 时忽略，处理链执行完成后交给 Engine.ErrorHandler 输出响应
+// 记录错误不会中断处理链，需要时调用 Context.Abort
+//
+//	ctx.Error(ctx.JSON(http.StatusOK, user))
+func (c *Context) Error(err error) {
+	if err != nil {
+		c.errs = append(c.errs, err)
+	}
+}
+
+// Errors 按记录顺序返回 Context.Error 记录的错误
+func (c *Context) Errors() []error {
+	return c.errs
+}
+
+// DefaultErrorHandler errors.Join 合并的多个错误逐个判断，使用第一个 HTTPError 输出响应，没有时使用第一个需要处理的错误
+// 状态码和消息来自 HTTPError，绑定和校验错误返回 400，其他错误沿用已经设置的 4xx、5xx 状态码，否则返回 500
+// 根据 Accept 输出 JSON {"message":"..."} 或 HTML 页面，非 HTTPError 的错误信息不会输出给客户端
+// Engine.BindErrorHandler 不为 nil 时绑定和校验错误已经由它输出，不再处理，响应已经提交时也不再处理
+var DefaultErrorHandler = func(ctx *Context, err error) {
+	if ctx.Committed() {
+		return
+	}
+	err, keepStatus := ctx.unhandledError(err)
+	if err == nil {
+		return
+	}
+
+	code, message := http.StatusInternalServerError, ""
+	var httpErr *HTTPError
+	switch {
+	case errors.As(err, &httpErr):
+		code, message = httpErr.Code, httpErr.Message
+	case isBindError(err):
+		code = http.StatusBadRequest
+	case keepStatus && ctx.StatusCode >= http.StatusBadRequest:
+		code = ctx.StatusCode
+	}
+	if message == "" {
+		message = http.StatusText(code)
+	}
+
+	ctx.Resp.Header().Add("Vary", "Accept")
+	// 客户端不接受 JSON 和 HTML 时仍然使用 JSON
+	r, mediaType, ok := negotiateRenderer(ctx.Req.Header.Values("Accept"), []Renderer{JSONRenderer{}, htmlErrorRenderer{}})
+	switch {
+	case !ok:
+		r, mediaType = JSONRenderer{}, "application/json"
+	case mediaType == "text/html":
+		mediaType = "text/html; charset=utf-8"
+	}
+	data, _ := r.Render(errorBody{Code: code, Message: message})
+	ctx.Resp.Header().Set("Content-Type", mediaType)
+	ctx.StatusCode = code
+	ctx.RespData = data
+}
+
+// unhandledError 返回还没有输出响应的错误，优先返回第一个 HTTPError，都已经处理时返回 nil
+// 跳过了 BindErrorHandler 输出的错误时，已经设置的状态码来自绑定错误，第二个返回值为 false
+func (c *Context) unhandledError(err error) (error, bool) {
+	errs := []error{err}
+	switch e := err.(type) {
+	case BindErrors, ValidationErrors:
+		// 本身也实现了 Unwrap() []error，作为一个错误处理
+	case interface{ Unwrap() []error }:
+		errs = e.Unwrap()
+	}
+	handled := c.engine == nil || c.engine.BindErrorHandler != nil
+	var res error
+	keepStatus := true
+	for _, e := range errs {
+		if handled && isBindError(e) {
+			keepStatus = false
+			continue
+		}
+		var httpErr *HTTPError
+		if errors.As(e, &httpErr) {
+			return e, keepStatus
+		}
+		if res == nil {
+			res = e
+		}
+	}
+	return res, keepStatus
+}
+
+func isBindError(err error) bool {
+	var bindErrs BindErrors
+	var validationErrs ValidationErrors
+	return errors.As(err, &bindErrs) || errors.As(err, &validationErrs)
+}
+
+type errorBody struct {
+	Code    int    `json:"-"`
+	Message string `json:"message"`
+}
+
+// htmlErrorRenderer 输出 DefaultErrorHandler 的错误页面
+type htmlErrorRenderer struct{}
+
+func (htmlErrorRenderer) MediaTypes() []string {
+	return []string{"text/html"}
+}
+
+func (htmlErrorRenderer) Render(data any) ([]byte, error) {
+	body := data.(errorBody)
+	title := fmt.Sprintf("%d %s", body.Code, http.StatusText(body.Code))
+	return []byte(fmt.Sprintf("<!DOCTYPE html>\n<html><head><title>%s</title></head><body><h1>%s</h1><p>%s</p></body></html>",
+		title, title, html.EscapeString(body.Message))), nil
+}
+
+// handleErrors 处理链执行完成后把记录的错误交给 Engine.ErrorHandler，有多个错误时合并为一个
+func (e *Engine) handleErrors(ctx *Context) {
+	if len(ctx.errs) == 0 || e.ErrorHandler == nil {
+		return
+	}
+	err := ctx.errs[0]
+	if len(ctx.errs) > 1 {
+		err = errors.Join(ctx.errs...)
+	}
+	e.ErrorHandler(ctx, err)
+}
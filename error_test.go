@@ -0,0 +1,185 @@
+package web
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPError(t *testing.T) {
+	errForbidden := NewHTTPError(http.StatusForbidden, "")
+	assert.Equal(t, "403 Forbidden", errForbidden.Error())
+
+	cause := errors.New("token expired")
+	err := errForbidden.WithInternal(cause)
+	assert.Equal(t, "403 Forbidden: token expired", err.Error())
+	assert.ErrorIs(t, err, cause)
+	assert.Nil(t, errForbidden.Internal)
+}
+
+func TestContext_Error(t *testing.T) {
+	testCases := []struct {
+		name            string
+		opts            []EngineOption
+		accept          string
+		handler         HandleFunc
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name: "no error",
+			handler: func(ctx *Context) {
+				ctx.Error(ctx.String(http.StatusOK, "ok"))
+			},
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/plain",
+			wantBody:        "ok",
+		},
+		{
+			name: "http error",
+			handler: func(ctx *Context) {
+				ctx.Error(NewHTTPError(http.StatusForbidden, "no permission").WithInternal(errors.New("secret")))
+			},
+			wantStatus:      http.StatusForbidden,
+			wantContentType: "application/json",
+			wantBody:        `{"message":"no permission"}`,
+		},
+		{
+			name:   "html",
+			accept: "text/html,application/xhtml+xml,*/*;q=0.8",
+			handler: func(ctx *Context) {
+				ctx.Error(NewHTTPError(http.StatusNotFound, "<user> not found"))
+			},
+			wantStatus:      http.StatusNotFound,
+			wantContentType: "text/html; charset=utf-8",
+			wantBody: "<!DOCTYPE html>\n<html><head><title>404 Not Found</title></head>" +
+				"<body><h1>404 Not Found</h1><p>&lt;user&gt; not found</p></body></html>",
+		},
+		{
+			name:   "not acceptable falls back to json",
+			accept: "image/png",
+			handler: func(ctx *Context) {
+				ctx.Error(NewHTTPError(http.StatusConflict, ""))
+			},
+			wantStatus:      http.StatusConflict,
+			wantContentType: "application/json",
+			wantBody:        `{"message":"Conflict"}`,
+		},
+		{
+			name: "internal error hidden",
+			handler: func(ctx *Context) {
+				_ = ctx.String(http.StatusOK, "partial")
+				ctx.Error(errors.New("db password wrong"))
+			},
+			wantStatus:      http.StatusInternalServerError,
+			wantContentType: "application/json",
+			wantBody:        `{"message":"Internal Server Error"}`,
+		},
+		{
+			name: "keep error status",
+			handler: func(ctx *Context) {
+				ctx.Error(ctx.BindBody(&bodyReq{}))
+			},
+			wantStatus:      http.StatusUnsupportedMediaType,
+			wantContentType: "application/json",
+			wantBody:        `{"message":"Unsupported Media Type"}`,
+		},
+		{
+			name: "bind error rendered by BindErrorHandler",
+			handler: func(ctx *Context) {
+				ctx.Error(Validate(&bodyReq{}))
+				ctx.Error(ctx.Bind(&bodyReq{}))
+			},
+			wantStatus:      http.StatusBadRequest,
+			wantContentType: "application/json",
+			wantBody: `{"message":"invalid request","errors":[{"field":"Name","rule":"required",` +
+				`"message":"field Name failed on rule required"}]}`,
+		},
+		{
+			name: "bind error without BindErrorHandler",
+			opts: []EngineOption{WithBindErrorHandler(nil)},
+			handler: func(ctx *Context) {
+				ctx.Req.Header.Set("Content-Type", "application/json")
+				ctx.Req.Body = io.NopCloser(strings.NewReader(`{}`))
+				ctx.Error(ctx.BindJSON(&bodyReq{}))
+			},
+			wantStatus:      http.StatusBadRequest,
+			wantContentType: "application/json",
+			wantBody:        `{"message":"Bad Request"}`,
+		},
+		{
+			name: "bind error does not hide other errors",
+			handler: func(ctx *Context) {
+				ctx.Error(ctx.Bind(&bodyReq{}))
+				ctx.Error(errors.New("db down"))
+			},
+			wantStatus:      http.StatusInternalServerError,
+			wantContentType: "application/json",
+			wantBody:        `{"message":"Internal Server Error"}`,
+		},
+		{
+			name: "first http error wins",
+			handler: func(ctx *Context) {
+				ctx.Error(errors.New("cause"))
+				ctx.Error(NewHTTPError(http.StatusUnauthorized, ""))
+				ctx.Error(NewHTTPError(http.StatusForbidden, ""))
+			},
+			wantStatus:      http.StatusUnauthorized,
+			wantContentType: "application/json",
+			wantBody:        `{"message":"Unauthorized"}`,
+		},
+		{
+			name: "committed",
+			handler: func(ctx *Context) {
+				_, _ = ctx.Writer().Write([]byte("streamed"))
+				ctx.Error(errors.New("client gone"))
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   "streamed",
+		},
+		{
+			name: "custom handler",
+			opts: []EngineOption{WithErrorHandler(func(ctx *Context, err error) {
+				_ = ctx.String(http.StatusTeapot, err.Error())
+			})},
+			handler: func(ctx *Context) {
+				ctx.Error(errors.New("a"))
+				ctx.Error(errors.New("b"))
+			},
+			wantStatus:      http.StatusTeapot,
+			wantContentType: "text/plain",
+			wantBody:        "a\nb",
+		},
+		{
+			name: "nil handler",
+			opts: []EngineOption{WithErrorHandler(nil)},
+			handler: func(ctx *Context) {
+				ctx.Error(errors.New("ignored"))
+			},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := NewEngine(tc.opts...)
+			e.POST("/users", tc.handler)
+			req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(""))
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			recorder := httptest.NewRecorder()
+			e.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantStatus, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+			if tc.wantContentType != "" {
+				assert.Equal(t, tc.wantContentType, recorder.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/prometheus/client_golang/prometheus"
 	"log"
+	"net/http"
 	"runtime"
 	"strconv"
 	"strings"
@@ -90,10 +91,12 @@ func (p PrometheusBuilder) Build() HandleFunc {
 	}
 }
 
+// RecoverBuilder 恢复 panic 后把 panic 作为 500 的 HTTPError 记录到 Context.Error，由 Engine.ErrorHandler 统一输出
 type RecoverBuilder struct {
 	LogFunc  func(log string)
 	LogStack bool
-	Handler  HandleFunc
+	// Handler 在记录错误之后调用，Engine.ErrorHandler 为 nil 时由它输出响应
+	Handler HandleFunc
 }
 
 func DefaultRecoverHandler(ctx *Context) {
@@ -116,6 +119,7 @@ func (r RecoverBuilder) Build() HandleFunc {
 				} else {
 					r.LogFunc(fmt.Sprintf("%s", err))
 				}
+				ctx.Error(NewHTTPError(http.StatusInternalServerError, "").WithInternal(panicError(err)))
 				r.Handler(ctx)
 			}
 		}()
@@ -123,6 +127,13 @@ func (r RecoverBuilder) Build() HandleFunc {
 	}
 }
 
+func panicError(val any) error {
+	if err, ok := val.(error); ok {
+		return fmt.Errorf("panic: %w", err)
+	}
+	return fmt.Errorf("panic: %v", val)
+}
+
 func trace(message string) string {
 	var pcs [32]uintptr
 	n := runtime.Callers(3, pcs[:])
@@ -1,7 +1,11 @@
 package web
 
 import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 )
@@ -45,3 +49,35 @@ func TestRecoverBuilder_Build(t *testing.T) {
 	}
 	server.ServeHTTP(&MockWriter{}, mockRequest)
 }
+
+func TestRecoverBuilder_errorHandler(t *testing.T) {
+	var logs []string
+	var recorded []error
+	server := NewEngine(WithErrorHandler(func(ctx *Context, err error) {
+		recorded = ctx.Errors()
+		DefaultErrorHandler(ctx, err)
+	}))
+	server.Use(RecoverBuilder{
+		LogFunc: func(log string) {
+			logs = append(logs, log)
+		},
+	}.Build())
+	server.GET("/test", func(c *Context) {
+		panic(errors.New("boom"))
+	})
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/test", nil))
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.Equal(t, `{"message":"Internal Server Error"}`, recorder.Body.String())
+	assert.Equal(t, []string{"boom"}, logs)
+	require.Len(t, recorded, 1)
+	assert.EqualError(t, recorded[0], "500 Internal Server Error: panic: boom")
+
+	// 没有 ErrorHandler 时由 RecoverBuilder.Handler 输出
+	server.ErrorHandler = nil
+	recorder = httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/test", nil))
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.Equal(t, "Internal Server Error", recorder.Body.String())
+}
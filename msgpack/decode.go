@@ -0,0 +1,518 @@
+package msgpack
+
+import (
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Unmarshal 解码到 val 指向的值，字段名规则和 Marshal 相同，结构体中不存在的 key 被忽略
+// data 可以来自不可信的输入，长度字段超过剩余数据时直接返回错误，不会按长度分配内存
+func Unmarshal(data []byte, val any) error {
+	return unmarshal(data, val, false)
+}
+
+// UnmarshalStrict 和 Unmarshal 相同，但结构体中不存在的 key 返回 ErrUnknownField
+func UnmarshalStrict(data []byte, val any) error {
+	return unmarshal(data, val, true)
+}
+
+func unmarshal(data []byte, val any, strict bool) error {
+	rv := reflect.ValueOf(val)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("msgpack: decode target must be a non-nil pointer")
+	}
+	d := &decoder{data: data}
+	src, err := d.decode()
+	if err != nil {
+		return err
+	}
+	if d.pos != len(d.data) {
+		return errors.New("msgpack: unexpected trailing data")
+	}
+	return assign(rv.Elem(), src, strict)
+}
+
+// pair map 的 key 可能是不能比较的类型，如 bin，所以解码为有序的键值对
+type pair struct {
+	key any
+	val any
+}
+
+type decoder struct {
+	data []byte
+	pos  int
+	// 当前所在的数组和 map 的层数
+	depth int
+}
+
+var errShort = errors.New("msgpack: unexpected end of data")
+
+func (d *decoder) next(n int) ([]byte, error) {
+	if n < 0 || len(d.data)-d.pos < n {
+		return nil, errShort
+	}
+	res := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return res, nil
+}
+
+// uint 读取 n 字节的大端无符号整数，n 为 1、2、4、8
+func (d *decoder) uint(n int) (uint64, error) {
+	b, err := d.next(n)
+	if err != nil {
+		return 0, err
+	}
+	var res uint64
+	for _, c := range b {
+		res = res<<8 | uint64(c)
+	}
+	return res, nil
+}
+
+// decode 解码为 nil、bool、int64、uint64、float64、string、[]byte、[]any、[]pair 或 time.Time
+func (d *decoder) decode() (any, error) {
+	b, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	c := b[0]
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xf0 == 0x80:
+		return d.decodeMap(int(c & 0x0f))
+	case c&0xf0 == 0x90:
+		return d.decodeArray(int(c & 0x0f))
+	case c&0xe0 == 0xa0:
+		return d.decodeString(int(c & 0x1f))
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.uint(1 << (c - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.next(int(n))
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), b...), nil
+	case 0xc7, 0xc8, 0xc9:
+		n, err := d.uint(1 << (c - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeExt(int(n))
+	case 0xca:
+		n, err := d.uint(4)
+		return float64(math.Float32frombits(uint32(n))), err
+	case 0xcb:
+		n, err := d.uint(8)
+		return math.Float64frombits(n), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return d.uint(1 << (c - 0xcc))
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (c - 0xd0)
+		n, err := d.uint(size)
+		// 符号扩展
+		shift := 64 - 8*size
+		return int64(n<<shift) >> shift, err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.decodeExt(1 << (c - 0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.uint(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeString(int(n))
+	case 0xdc, 0xdd:
+		n, err := d.uint(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeArray(int(n))
+	case 0xde, 0xdf:
+		n, err := d.uint(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeMap(int(n))
+	}
+	return nil, fmt.Errorf("msgpack: invalid format byte 0x%x", c)
+}
+
+func (d *decoder) decodeString(n int) (any, error) {
+	b, err := d.next(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (d *decoder) decodeArray(n int) (any, error) {
+	d.depth++
+	defer func() { d.depth-- }()
+	if d.depth > MaxDepth {
+		return nil, ErrMaxDepth
+	}
+	// 每个元素至少占 1 字节，避免按恶意的长度分配内存
+	if n > len(d.data)-d.pos {
+		return nil, errShort
+	}
+	res := make([]any, n)
+	for i := range res {
+		val, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		res[i] = val
+	}
+	return res, nil
+}
+
+func (d *decoder) decodeMap(n int) (any, error) {
+	d.depth++
+	defer func() { d.depth-- }()
+	if d.depth > MaxDepth {
+		return nil, ErrMaxDepth
+	}
+	if n > (len(d.data)-d.pos)/2 {
+		return nil, errShort
+	}
+	res := make([]pair, n)
+	for i := range res {
+		key, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		val, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		res[i] = pair{key: key, val: val}
+	}
+	return res, nil
+}
+
+// decodeExt 只支持 timestamp 扩展类型
+func (d *decoder) decodeExt(n int) (any, error) {
+	typ, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	b, err := d.next(n)
+	if err != nil {
+		return nil, err
+	}
+	if int8(typ[0]) != -1 {
+		return nil, fmt.Errorf("msgpack: unsupported ext type %d", int8(typ[0]))
+	}
+	switch n {
+	case 4:
+		return time.Unix(int64(binary.BigEndian.Uint32(b)), 0), nil
+	case 8:
+		v := binary.BigEndian.Uint64(b)
+		return time.Unix(int64(v&(1<<34-1)), int64(v>>34)), nil
+	case 12:
+		return time.Unix(int64(binary.BigEndian.Uint64(b[4:])), int64(binary.BigEndian.Uint32(b))), nil
+	}
+	return nil, fmt.Errorf("msgpack: invalid timestamp length %d", n)
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+func assign(v reflect.Value, src any, strict bool) error {
+	if src == nil {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return assign(v.Elem(), src, strict)
+	}
+	if v.Kind() == reflect.Interface && v.NumMethod() == 0 {
+		val, err := toInterface(src)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(val))
+		return nil
+	}
+	if t, ok := src.(time.Time); ok && v.Type() == timeType {
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+	if s, ok := src.(string); ok && reflect.PointerTo(v.Type()).Implements(textUnmarshalerType) && v.Type() != timeType {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	mismatch := fmt.Errorf("msgpack: cannot decode %T into %s", src, v.Type())
+	switch v.Kind() {
+	case reflect.Bool:
+		b, ok := src.(bool)
+		if !ok {
+			return mismatch
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		switch val := src.(type) {
+		case int64:
+			n = val
+		case uint64:
+			if val > math.MaxInt64 {
+				return mismatch
+			}
+			n = int64(val)
+		default:
+			return mismatch
+		}
+		if v.OverflowInt(n) {
+			return fmt.Errorf("msgpack: %d overflows %s", n, v.Type())
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var n uint64
+		switch val := src.(type) {
+		case uint64:
+			n = val
+		case int64:
+			if val < 0 {
+				return mismatch
+			}
+			n = uint64(val)
+		default:
+			return mismatch
+		}
+		if v.OverflowUint(n) {
+			return fmt.Errorf("msgpack: %d overflows %s", n, v.Type())
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		switch val := src.(type) {
+		case float64:
+			v.SetFloat(val)
+		case int64:
+			v.SetFloat(float64(val))
+		case uint64:
+			v.SetFloat(float64(val))
+		default:
+			return mismatch
+		}
+	case reflect.String:
+		switch val := src.(type) {
+		case string:
+			v.SetString(val)
+		case []byte:
+			v.SetString(string(val))
+		default:
+			return mismatch
+		}
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			switch val := src.(type) {
+			case []byte:
+				v.SetBytes(val)
+				return nil
+			case string:
+				v.SetBytes([]byte(val))
+				return nil
+			}
+		}
+		arr, ok := src.([]any)
+		if !ok {
+			return mismatch
+		}
+		slice := reflect.MakeSlice(v.Type(), len(arr), len(arr))
+		for i, item := range arr {
+			if err := assign(slice.Index(i), item, strict); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	case reflect.Array:
+		arr, ok := src.([]any)
+		if !ok || len(arr) > v.Len() {
+			return mismatch
+		}
+		for i, item := range arr {
+			if err := assign(v.Index(i), item, strict); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		pairs, ok := src.([]pair)
+		if !ok {
+			return mismatch
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMapWithSize(v.Type(), len(pairs)))
+		}
+		for _, p := range pairs {
+			key := reflect.New(v.Type().Key()).Elem()
+			if err := assign(key, p.key, strict); err != nil {
+				return err
+			}
+			val := reflect.New(v.Type().Elem()).Elem()
+			if err := assign(val, p.val, strict); err != nil {
+				return err
+			}
+			v.SetMapIndex(key, val)
+		}
+	case reflect.Struct:
+		pairs, ok := src.([]pair)
+		if !ok {
+			return mismatch
+		}
+		fields := fieldIndex(v.Type())
+		for _, p := range pairs {
+			name, ok := p.key.(string)
+			if !ok {
+				return mismatch
+			}
+			index, ok := fields[name]
+			if !ok {
+				if strict {
+					return fmt.Errorf("%w: %s", ErrUnknownField, name)
+				}
+				continue
+			}
+			fv, err := fieldByIndex(v, index)
+			if err != nil {
+				return err
+			}
+			if err = assign(fv, p.val, strict); err != nil {
+				return err
+			}
+		}
+	default:
+		return mismatch
+	}
+	return nil
+}
+
+// toInterface 解码到 any 时 map 使用 map[string]any，key 不全是字符串时使用 map[any]any
+func toInterface(src any) (any, error) {
+	switch val := src.(type) {
+	case []any:
+		for i, item := range val {
+			res, err := toInterface(item)
+			if err != nil {
+				return nil, err
+			}
+			val[i] = res
+		}
+		return val, nil
+	case []pair:
+		strKeys := make(map[string]any, len(val))
+		anyKeys := make(map[any]any, len(val))
+		for _, p := range val {
+			item, err := toInterface(p.val)
+			if err != nil {
+				return nil, err
+			}
+			if key, ok := p.key.(string); ok {
+				strKeys[key] = item
+			}
+			if p.key != nil && !reflect.TypeOf(p.key).Comparable() {
+				return nil, fmt.Errorf("msgpack: invalid map key type %T", p.key)
+			}
+			anyKeys[p.key] = item
+		}
+		if len(strKeys) == len(val) {
+			return strKeys, nil
+		}
+		return anyKeys, nil
+	}
+	return src, nil
+}
+
+var fieldIndexCache sync.Map
+
+func cachedFieldIndex(typ reflect.Type) map[string][]int {
+	if res, ok := fieldIndexCache.Load(typ); ok {
+		return res.(map[string][]int)
+	}
+	res, _ := fieldIndexCache.LoadOrStore(typ, fieldIndex(typ))
+	return res.(map[string][]int)
+}
+
+// fieldIndex 字段名到 index 的映射，规则和编码时相同
+// 匿名字段可能间接包含自身，如 type T struct{ *T }，已经展开过的类型不再展开
+func fieldIndex(typ reflect.Type) map[string][]int {
+	res := make(map[string][]int, typ.NumField())
+	visited := map[reflect.Type]bool{}
+	var walk func(typ reflect.Type, index []int)
+	walk = func(typ reflect.Type, index []int) {
+		visited[typ] = true
+		for i := 0; i < typ.NumField(); i++ {
+			sf := typ.Field(i)
+			tag, ok := sf.Tag.Lookup("msgpack")
+			if !ok {
+				tag = sf.Tag.Get("json")
+			}
+			if tag == "-" {
+				continue
+			}
+			name, _, _ := strings.Cut(tag, ",")
+			index := append(append([]int(nil), index...), i)
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+				if !visited[ft] {
+					walk(ft, index)
+				}
+				continue
+			}
+			if !sf.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = sf.Name
+			}
+			// 外层的字段优先
+			if old, ok := res[name]; !ok || len(old) > len(index) {
+				res[name] = index
+			}
+		}
+	}
+	walk(typ, nil)
+	return res
+}
+
+// fieldByIndex 和 reflect.Value.FieldByIndex 相同，但会为 nil 的匿名结构体指针分配内存
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, idx := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, fmt.Errorf("msgpack: cannot set embedded pointer to unexported struct %s", v.Type().Elem())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(idx)
+	}
+	return v, nil
+}
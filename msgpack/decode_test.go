@@ -0,0 +1,124 @@
+package msgpack
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+type decodeReq struct {
+	inner
+	Name    string            `msgpack:"name"`
+	Score   float64           `msgpack:"score"`
+	Small   int8              `msgpack:"small"`
+	Raw     []byte            `msgpack:"raw"`
+	Attrs   map[string]uint16 `msgpack:"attrs"`
+	Parent  *decodeReq        `msgpack:"parent"`
+	Extra   any               `msgpack:"extra"`
+	Created time.Time         `msgpack:"created"`
+}
+
+func TestUnmarshal(t *testing.T) {
+	src := map[string]any{
+		"id":      -100000,
+		"name":    "tom",
+		"score":   float32(1.5),
+		"small":   -5,
+		"raw":     []byte{1, 2},
+		"attrs":   map[string]int{"a": 1},
+		"parent":  map[string]any{"name": "jerry"},
+		"extra":   map[string]any{"list": []any{1, "x", nil}},
+		"created": time.Unix(1700000000, 5).UTC(),
+	}
+	data, err := Marshal(src)
+	require.NoError(t, err)
+
+	var res decodeReq
+	require.NoError(t, UnmarshalStrict(data, &res))
+	assert.Equal(t, decodeReq{
+		inner:   inner{ID: -100000},
+		Name:    "tom",
+		Score:   1.5,
+		Small:   -5,
+		Raw:     []byte{1, 2},
+		Attrs:   map[string]uint16{"a": 1},
+		Parent:  &decodeReq{Name: "jerry"},
+		Extra:   map[string]any{"list": []any{int64(1), "x", nil}},
+		Created: time.Unix(1700000000, 5),
+	}, res)
+
+	testCases := []struct {
+		name string
+		data []byte
+	}{
+		{name: "overflow", data: []byte{0x81, 0xa5, 's', 'm', 'a', 'l', 'l', 0xcc, 0xc8}},
+		{name: "type mismatch", data: []byte{0x81, 0xa4, 'n', 'a', 'm', 'e', 0x01}},
+		{name: "unknown field", data: []byte{0x81, 0xa1, 'x', 0x01}},
+		{name: "truncated", data: []byte{0x82, 0xa4, 'n', 'a'}},
+		{name: "huge array length", data: []byte{0xdd, 0xff, 0xff, 0xff, 0xff}},
+		{name: "huge map length", data: []byte{0xdf, 0xff, 0xff, 0xff, 0xff}},
+		{name: "huge str length", data: []byte{0xdb, 0xff, 0xff, 0xff, 0xff, 'a'}},
+		{name: "huge bin length", data: []byte{0xc6, 0xff, 0xff, 0xff, 0xff, 0x01}},
+		{name: "unsupported ext", data: []byte{0xd4, 0x01, 0x00}},
+		{name: "invalid timestamp length", data: []byte{0xc7, 0x03, 0xff, 0, 0, 0}},
+		{name: "unhashable key", data: []byte{0x81, 0xa5, 'e', 'x', 't', 'r', 'a', 0x81, 0x90, 0x01}},
+		{name: "trailing data", data: []byte{0x80, 0x00}},
+		{name: "invalid format", data: []byte{0xc1}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Error(t, UnmarshalStrict(tc.data, &decodeReq{}))
+		})
+	}
+}
+
+func TestUnmarshal_unknownField(t *testing.T) {
+	data := []byte{0x82, 0xa4, 'n', 'a', 'm', 'e', 0xa3, 't', 'o', 'm', 0xa1, 'x', 0x01}
+	var res decodeReq
+	require.NoError(t, Unmarshal(data, &res))
+	assert.Equal(t, "tom", res.Name)
+	assert.ErrorIs(t, UnmarshalStrict(data, &decodeReq{}), ErrUnknownField)
+
+	var ptr *decodeReq
+	assert.Error(t, Unmarshal(data, ptr))
+	assert.Error(t, Unmarshal(data, res))
+}
+
+func TestUnmarshal_maxDepth(t *testing.T) {
+	nested := func(n int) []byte {
+		return append(bytes.Repeat([]byte{0x91}, n), 0xc0)
+	}
+	var v any
+	require.NoError(t, Unmarshal(nested(MaxDepth), &v))
+	assert.ErrorIs(t, Unmarshal(nested(MaxDepth+1), &v), ErrMaxDepth)
+	// 远超限制的输入不能导致栈溢出
+	assert.ErrorIs(t, Unmarshal(nested(20<<20), &v), ErrMaxDepth)
+	assert.ErrorIs(t, Unmarshal(append(bytes.Repeat([]byte{0x81, 0xa1, 'a'}, MaxDepth+1), 0xc0), &v), ErrMaxDepth)
+}
+
+type recursive struct {
+	*recursive
+	Name string
+}
+
+func TestUnmarshal_recursiveEmbedded(t *testing.T) {
+	var res recursive
+	require.NoError(t, Unmarshal([]byte{0x81, 0xa4, 'N', 'a', 'm', 'e', 0xa1, 'a'}, &res))
+	assert.Equal(t, "a", res.Name)
+}
+
+// FuzzUnmarshal 任意输入都不能 panic
+func FuzzUnmarshal(f *testing.F) {
+	seed, err := Marshal(map[string]any{"name": "tom", "attrs": map[string]int{"a": 1}, "extra": []any{1, "x", nil}})
+	require.NoError(f, err)
+	f.Add(seed)
+	f.Add([]byte{0xdd, 0xff, 0xff, 0xff, 0xff})
+	f.Add([]byte{0xc7, 12, 0xff, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 1})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_ = Unmarshal(data, &decodeReq{})
+		var v any
+		_ = Unmarshal(data, &v)
+	})
+}
@@ -0,0 +1,96 @@
+package msgpack
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+type inner struct {
+	ID int64 `msgpack:"id"`
+}
+
+type user struct {
+	inner
+	Name    string            `json:"name"`
+	Nick    string            `json:"nick,omitempty"`
+	Skip    string            `msgpack:"-"`
+	Score   float64           `msgpack:"score"`
+	Active  bool              `msgpack:"active"`
+	Tags    []string          `msgpack:"tags"`
+	Raw     []byte            `msgpack:"raw"`
+	Attrs   map[string]uint16 `msgpack:"attrs"`
+	Parent  *user             `msgpack:"parent"`
+	Created time.Time         `msgpack:"created"`
+}
+
+func TestMarshal(t *testing.T) {
+	testCases := []struct {
+		name string
+		data any
+		want []byte
+	}{
+		{name: "nil", data: nil, want: []byte{0xc0}},
+		{name: "positive fixint", data: 127, want: []byte{0x7f}},
+		{name: "negative fixint", data: -32, want: []byte{0xe0}},
+		{name: "uint8", data: 200, want: []byte{0xcc, 0xc8}},
+		{name: "int8", data: -100, want: []byte{0xd0, 0x9c}},
+		{name: "uint16", data: 1000, want: []byte{0xcd, 0x03, 0xe8}},
+		{name: "int32", data: int32(-100000), want: []byte{0xd2, 0xff, 0xfe, 0x79, 0x60}},
+		{name: "float32", data: float32(1.5), want: []byte{0xca, 0x3f, 0xc0, 0x00, 0x00}},
+		{name: "str8", data: strings.Repeat("a", 32), want: append([]byte{0xd9, 32}, strings.Repeat("a", 32)...)},
+		{name: "array16", data: make([]int, 16), want: append([]byte{0xdc, 0x00, 0x10}, make([]byte, 16)...)},
+		{
+			name: "struct",
+			data: user{
+				inner:   inner{ID: 1},
+				Name:    "tom",
+				Skip:    "x",
+				Score:   0.5,
+				Active:  true,
+				Tags:    []string{"a"},
+				Raw:     []byte{1, 2},
+				Attrs:   map[string]uint16{"b": 2, "a": 1},
+				Created: time.Unix(1, 2),
+			},
+			want: []byte{
+				0x89,
+				0xa2, 'i', 'd', 0x01,
+				0xa4, 'n', 'a', 'm', 'e', 0xa3, 't', 'o', 'm',
+				0xa5, 's', 'c', 'o', 'r', 'e', 0xcb, 0x3f, 0xe0, 0, 0, 0, 0, 0, 0,
+				0xa6, 'a', 'c', 't', 'i', 'v', 'e', 0xc3,
+				0xa4, 't', 'a', 'g', 's', 0x91, 0xa1, 'a',
+				0xa3, 'r', 'a', 'w', 0xc4, 0x02, 1, 2,
+				0xa5, 'a', 't', 't', 'r', 's', 0x82, 0xa1, 'a', 0x01, 0xa1, 'b', 0x02,
+				0xa6, 'p', 'a', 'r', 'e', 'n', 't', 0xc0,
+				0xa7, 'c', 'r', 'e', 'a', 't', 'e', 'd', 0xc7, 12, 0xff, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 1,
+			},
+		},
+		{name: "unsupported", data: make(chan int)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := Marshal(tc.data)
+			if tc.want == nil {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, res)
+		})
+	}
+}
+
+type cycle struct {
+	Next *cycle
+}
+
+func TestMarshal_cycle(t *testing.T) {
+	c := &cycle{}
+	c.Next = c
+	_, err := Marshal(c)
+	assert.ErrorIs(t, err, ErrMaxDepth)
+}
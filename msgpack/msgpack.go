@@ -0,0 +1,20 @@
+// Package msgpack 实现 MessagePack 的编码和解码，只支持 timestamp 扩展类型
+package msgpack
+
+import (
+	"errors"
+	"reflect"
+	"time"
+)
+
+var (
+	// ErrUnknownField UnmarshalStrict 遇到结构体中不存在的 key
+	ErrUnknownField = errors.New("msgpack: unknown field")
+	// ErrMaxDepth 数组和 map 的嵌套层数超过 MaxDepth，避免恶意输入导致栈溢出
+	ErrMaxDepth = errors.New("msgpack: exceeded max depth")
+)
+
+// MaxDepth 编码和解码时的最大嵌套层数，和 encoding/json 相同，编码时也用于发现循环引用
+const MaxDepth = 10000
+
+var timeType = reflect.TypeOf(time.Time{})
@@ -0,0 +1,45 @@
+package web
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// paramTypes 路径参数类型约束，:id<int> 中的 int 即为类型名
+var paramTypes = map[string]*regexp.Regexp{
+	"int":   regexp.MustCompile(`^-?\d+$`),
+	"uint":  regexp.MustCompile(`^\d+$`),
+	"alpha": regexp.MustCompile(`^[a-zA-Z]+$`),
+	"alnum": regexp.MustCompile(`^[a-zA-Z0-9]+$`),
+	"uuid":  regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`),
+}
+
+// RegisterParamType 注册自定义的路径参数类型，需要在注册路由之前调用
+// pattern 会被完整匹配，不需要自己加 ^ 和 $
+func RegisterParamType(name string, pattern string) {
+	paramTypes[name] = regexp.MustCompile(anchor(pattern))
+}
+
+// parseParam 解析路径参数段，返回参数名和约束
+// 支持 :name、:name<type>、:name(regexp) 三种形式
+func parseParam(seg string) (string, *regexp.Regexp) {
+	s := seg[1:]
+	if i := strings.IndexByte(s, '<'); i >= 0 && strings.HasSuffix(s, ">") {
+		typ := s[i+1 : len(s)-1]
+		constraint, ok := paramTypes[typ]
+		if !ok {
+			panic(fmt.Sprintf("unknown param type: %s", typ))
+		}
+		return s[:i], constraint
+	}
+	if i := strings.IndexByte(s, '('); i >= 0 && strings.HasSuffix(s, ")") {
+		return s[:i], regexp.MustCompile(anchor(s[i+1 : len(s)-1]))
+	}
+	return s, nil
+}
+
+// anchor 保证正则匹配整个路径段
+func anchor(pattern string) string {
+	return "^(?:" + pattern + ")$"
+}
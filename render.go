@@ -0,0 +1,230 @@
+package web
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/KNICEX/go-web/msgpack"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ErrNotAcceptable 没有渲染器能提供 Accept 中要求的格式
+var ErrNotAcceptable = errors.New("not acceptable")
+
+// Renderer 把数据编码为某种格式的响应体
+type Renderer interface {
+	// MediaTypes 能提供的媒体类型，第一个是主类型，其余是只参与精确匹配的别名
+	// 响应的 Content-Type 是 Accept 匹配到的类型
+	MediaTypes() []string
+	Render(data any) ([]byte, error)
+}
+
+// renderers 按注册顺序排列，Accept 为空或只有 */* 时使用第一个
+var renderers = []Renderer{
+	JSONRenderer{},
+	XMLRenderer{},
+	YAMLRenderer{},
+	MsgPackRenderer{},
+	ProtobufRenderer{},
+	TextRenderer{},
+}
+
+// RegisterRenderer 注册渲染器，第一个媒体类型和已有的渲染器相同时替换已有的，需要在处理请求之前调用
+func RegisterRenderer(r Renderer) {
+	for i, old := range renderers {
+		if old.MediaTypes()[0] == r.MediaTypes()[0] {
+			renderers[i] = r
+			return
+		}
+	}
+	renderers = append(renderers, r)
+}
+
+type JSONRenderer struct{}
+
+func (JSONRenderer) MediaTypes() []string {
+	return []string{"application/json"}
+}
+
+func (JSONRenderer) Render(data any) ([]byte, error) {
+	return json.Marshal(data)
+}
+
+type XMLRenderer struct{}
+
+func (XMLRenderer) MediaTypes() []string {
+	return []string{"application/xml", "text/xml"}
+}
+
+func (XMLRenderer) Render(data any) ([]byte, error) {
+	return xml.Marshal(data)
+}
+
+type YAMLRenderer struct{}
+
+func (YAMLRenderer) MediaTypes() []string {
+	return []string{"application/yaml", "application/x-yaml", "text/yaml"}
+}
+
+func (YAMLRenderer) Render(data any) ([]byte, error) {
+	return yaml.Marshal(data)
+}
+
+// MsgPackRenderer 结构体字段名优先使用 msgpack tag，其次是 json tag
+type MsgPackRenderer struct{}
+
+func (MsgPackRenderer) MediaTypes() []string {
+	return []string{"application/msgpack", "application/x-msgpack"}
+}
+
+func (MsgPackRenderer) Render(data any) ([]byte, error) {
+	return msgpack.Marshal(data)
+}
+
+// ProtobufRenderer 数据必须是 proto.Message
+type ProtobufRenderer struct{}
+
+func (ProtobufRenderer) MediaTypes() []string {
+	return []string{"application/x-protobuf", "application/protobuf"}
+}
+
+func (ProtobufRenderer) Render(data any) ([]byte, error) {
+	msg, ok := data.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("protobuf render: %T is not a proto.Message", data)
+	}
+	return proto.Marshal(msg)
+}
+
+// TextRenderer 字符串和 []byte 原样输出，其他类型使用 fmt.Sprint
+type TextRenderer struct{}
+
+func (TextRenderer) MediaTypes() []string {
+	return []string{"text/plain"}
+}
+
+func (TextRenderer) Render(data any) ([]byte, error) {
+	switch val := data.(type) {
+	case string:
+		return []byte(val), nil
+	case []byte:
+		return val, nil
+	}
+	return []byte(fmt.Sprint(data)), nil
+}
+
+// Negotiate 根据 Accept 请求头选择渲染器输出 data，按 q 值从高到低选择，q 值相同时优先更具体的类型，其次是 Accept 中的顺序
+// 没有 Accept 时使用第一个注册的渲染器(JSON)，没有能提供的格式时返回 406 和 ErrNotAcceptable
+//
+//	func(ctx *web.Context) {
+//		_ = ctx.Negotiate(http.StatusOK, user)
+//	}
+func (c *Context) Negotiate(status int, data any) error {
+	c.Resp.Header().Add("Vary", "Accept")
+	r, mediaType, ok := negotiateRenderer(c.Req.Header.Values("Accept"), renderers)
+	if !ok {
+		c.StatusCode = http.StatusNotAcceptable
+		c.RespData = []byte("406 not acceptable")
+		return ErrNotAcceptable
+	}
+	res, err := r.Render(data)
+	if err != nil {
+		return err
+	}
+	c.Resp.Header().Set("Content-Type", mediaType)
+	c.StatusCode = status
+	c.RespData = res
+	return nil
+}
+
+type acceptRange struct {
+	typ     string
+	subtype string
+	q       float64
+}
+
+// specificity */* 为 0，type/* 为 1，type/subtype 为 2
+func (a acceptRange) specificity() int {
+	switch {
+	case a.typ == "*":
+		return 0
+	case a.subtype == "*":
+		return 1
+	}
+	return 2
+}
+
+func (a acceptRange) match(mediaType string) bool {
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+	return (a.typ == "*" || a.typ == typ) && (a.subtype == "*" || a.subtype == subtype)
+}
+
+// parseAccept 解析 Accept，忽略格式错误的项
+func parseAccept(header []string) []acceptRange {
+	var res []acceptRange
+	for _, line := range header {
+		for _, item := range strings.Split(line, ",") {
+			mediaType, params, _ := strings.Cut(item, ";")
+			typ, subtype, ok := strings.Cut(strings.ToLower(strings.TrimSpace(mediaType)), "/")
+			if !ok || typ == "" || subtype == "" || (typ == "*" && subtype != "*") {
+				continue
+			}
+			r := acceptRange{typ: typ, subtype: subtype, q: 1}
+			for _, param := range strings.Split(params, ";") {
+				key, val, _ := strings.Cut(param, "=")
+				if strings.TrimSpace(key) != "q" {
+					continue
+				}
+				q, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+				if err == nil && q >= 0 && q <= 1 {
+					r.q = q
+				}
+			}
+			res = append(res, r)
+		}
+	}
+	return res
+}
+
+// negotiateRenderer 每个媒体类型的 q 值取 Accept 中最具体的匹配项，q 为 0 表示拒绝
+func negotiateRenderer(header []string, candidates []Renderer) (Renderer, string, bool) {
+	accepts := parseAccept(header)
+	if len(accepts) == 0 {
+		if len(candidates) == 0 {
+			return nil, "", false
+		}
+		return candidates[0], candidates[0].MediaTypes()[0], true
+	}
+
+	var (
+		best          Renderer
+		bestType      string
+		bestQ         float64
+		bestSpecific  = -1
+		bestAcceptIdx int
+	)
+	for _, r := range candidates {
+		for j, mediaType := range r.MediaTypes() {
+			q, specific, idx := 0.0, -1, 0
+			for i, a := range accepts {
+				if a.match(mediaType) && a.specificity() > specific {
+					q, specific, idx = a.q, a.specificity(), i
+				}
+			}
+			// 别名只参与精确匹配，避免 text/* 选中 text/xml
+			if specific < 0 || q == 0 || (j > 0 && specific < 2) {
+				continue
+			}
+			if best == nil || q > bestQ ||
+				(q == bestQ && (specific > bestSpecific || (specific == bestSpecific && idx < bestAcceptIdx))) {
+				best, bestType, bestQ, bestSpecific, bestAcceptIdx = r, mediaType, q, specific, idx
+			}
+		}
+	}
+	return best, bestType, best != nil
+}
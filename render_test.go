@@ -0,0 +1,171 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type renderUser struct {
+	Name string `json:"name" xml:"name" yaml:"name"`
+	Age  int    `json:"age" xml:"age" yaml:"age"`
+}
+
+func (u renderUser) String() string {
+	return u.Name + ":" + strconv.Itoa(u.Age)
+}
+
+func TestContext_Negotiate(t *testing.T) {
+	user := renderUser{Name: "tom", Age: 18}
+	testCases := []struct {
+		name            string
+		accept          []string
+		data            any
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+		wantErr         error
+	}{
+		{
+			name:            "no accept",
+			data:            user,
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json",
+			wantBody:        `{"name":"tom","age":18}`,
+		},
+		{
+			name:            "any",
+			accept:          []string{"*/*"},
+			data:            user,
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json",
+			wantBody:        `{"name":"tom","age":18}`,
+		},
+		{
+			name:            "browser",
+			accept:          []string{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"},
+			data:            user,
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/xml",
+			wantBody:        `<renderUser><name>tom</name><age>18</age></renderUser>`,
+		},
+		{
+			name:            "q value",
+			accept:          []string{"application/json;q=0.5, application/yaml"},
+			data:            user,
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/yaml",
+			wantBody:        "name: tom\nage: 18\n",
+		},
+		{
+			name:            "more specific wins",
+			accept:          []string{"text/*;q=0.8, text/plain;q=0.9, */*;q=0.1"},
+			data:            user,
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/plain",
+			wantBody:        "tom:18",
+		},
+		{
+			name:            "accept order",
+			accept:          []string{"application/x-yaml", "application/xml"},
+			data:            user,
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/x-yaml",
+			wantBody:        "name: tom\nage: 18\n",
+		},
+		{
+			name:            "alias exact match",
+			accept:          []string{"text/xml"},
+			data:            user,
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/xml",
+			wantBody:        `<renderUser><name>tom</name><age>18</age></renderUser>`,
+		},
+		{
+			name:            "rejected by q=0",
+			accept:          []string{"*/*, application/json;q=0"},
+			data:            user,
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/xml",
+			wantBody:        `<renderUser><name>tom</name><age>18</age></renderUser>`,
+		},
+		{
+			name:            "protobuf",
+			accept:          []string{"application/x-protobuf"},
+			data:            wrapperspb.String("hi"),
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/x-protobuf",
+			wantBody:        string(mustMarshalProto(t, wrapperspb.String("hi"))),
+		},
+		{
+			name:       "not acceptable",
+			accept:     []string{"image/png, text/html;q=0.9"},
+			data:       user,
+			wantStatus: http.StatusNotAcceptable,
+			wantBody:   "406 not acceptable",
+			wantErr:    ErrNotAcceptable,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := NewEngine()
+			e.GET("/user", func(ctx *Context) {
+				err := ctx.Negotiate(http.StatusOK, tc.data)
+				assert.Equal(t, tc.wantErr, err)
+			})
+			req := httptest.NewRequest(http.MethodGet, "/user", nil)
+			req.Header["Accept"] = tc.accept
+			recorder := httptest.NewRecorder()
+			e.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantStatus, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+			assert.Equal(t, "Accept", recorder.Header().Get("Vary"))
+			if tc.wantContentType != "" {
+				assert.Equal(t, tc.wantContentType, recorder.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func mustMarshalProto(t *testing.T, msg proto.Message) []byte {
+	data, err := proto.Marshal(msg)
+	require.NoError(t, err)
+	return data
+}
+
+func TestProtobufRenderer(t *testing.T) {
+	_, err := ProtobufRenderer{}.Render(renderUser{})
+	assert.Error(t, err)
+}
+
+type csvRenderer struct{}
+
+func (csvRenderer) MediaTypes() []string {
+	return []string{"text/csv"}
+}
+
+func (csvRenderer) Render(data any) ([]byte, error) {
+	return []byte(strings.Join(data.([]string), ",")), nil
+}
+
+func TestRegisterRenderer(t *testing.T) {
+	old := renderers
+	defer func() {
+		renderers = old
+	}()
+	renderers = append([]Renderer(nil), renderers...)
+	RegisterRenderer(csvRenderer{})
+
+	ctx := newContext(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	ctx.Req.Header.Set("Accept", "text/csv")
+	require.NoError(t, ctx.Negotiate(http.StatusOK, []string{"a", "b"}))
+	assert.Equal(t, "a,b", string(ctx.RespData))
+	assert.Equal(t, "text/csv", ctx.Resp.Header().Get("Content-Type"))
+}
@@ -0,0 +1,145 @@
+package web
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+)
+
+// ResponseWriter 直接写入客户端的响应，第一次写入时提交响应
+// 响应提交后 Engine 不再输出 Context.StatusCode 和 Context.RespData
+type ResponseWriter interface {
+	http.ResponseWriter
+	http.Flusher
+	// Status 已提交的状态码，未提交时为 0
+	Status() int
+	// Size 已写入的响应体字节数
+	Size() int
+	// Committed 响应头是否已经写出
+	Committed() bool
+}
+
+var (
+	_ ResponseWriter = &responseWriter{}
+	_ http.Hijacker  = &responseWriter{}
+)
+
+// responseWriter 包装 http.ResponseWriter，记录响应是否已经提交
+type responseWriter struct {
+	http.ResponseWriter
+	ctx    *Context
+	status int
+	size   int
+}
+
+func (w *responseWriter) reset(rw http.ResponseWriter, ctx *Context) {
+	w.ResponseWriter = rw
+	w.ctx = ctx
+	w.status = 0
+	w.size = 0
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.status != 0 || status == 0 {
+		return
+	}
+	if status >= 100 && status < 200 && status != http.StatusSwitchingProtocols {
+		// 1xx 信息响应可以发送多次，不提交响应
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.status = status
+	w.ctx.StatusCode = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// writeHeaderNow 未提交时使用 Context.StatusCode 提交响应，没有设置时为 200
+func (w *responseWriter) writeHeaderNow() {
+	if w.status != 0 {
+		return
+	}
+	status := w.ctx.StatusCode
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(data []byte) (int, error) {
+	w.writeHeaderNow()
+	n, err := w.ResponseWriter.Write(data)
+	w.size += n
+	return n, err
+}
+
+func (w *responseWriter) Flush() {
+	w.writeHeaderNow()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *responseWriter) Status() int {
+	return w.status
+}
+
+func (w *responseWriter) Size() int {
+	return w.size
+}
+
+func (w *responseWriter) Committed() bool {
+	return w.status != 0
+}
+
+// Hijack 接管底层连接，接管后响应视为已经提交
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil && w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+		w.ctx.StatusCode = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// Unwrap 用于 http.ResponseController 访问原始的 http.ResponseWriter
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Writer 返回直接写入客户端的 ResponseWriter，写入前设置的 Context.StatusCode 作为响应的状态码
+func (c *Context) Writer() ResponseWriter {
+	return &c.writer
+}
+
+// Committed 响应是否已经通过 Writer 写出，middleware 可以据此判断能否再修改响应
+func (c *Context) Committed() bool {
+	return c.writer.Committed()
+}
+
+// Stream 反复调用 step 直接向客户端写入响应，每次调用后 flush，step 返回 false 或客户端断开连接时结束
+// 返回值表示客户端是否已经断开
+//
+//	ctx.Stream(func(w io.Writer) bool {
+//		msg, ok := <-ch
+//		if ok {
+//			_, _ = w.Write(msg)
+//		}
+//		return ok
+//	})
+func (c *Context) Stream(step func(w io.Writer) bool) bool {
+	w := c.Writer()
+	done := c.Req.Context().Done()
+	for {
+		select {
+		case <-done:
+			return true
+		default:
+			keep := step(w)
+			w.Flush()
+			if !keep {
+				return false
+			}
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package web
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestContext_Stream(t *testing.T) {
+	e := NewEngine()
+	committed := false
+	e.Use(func(ctx *Context) {
+		ctx.Next()
+		// 响应提交后 middleware 不能再修改响应
+		committed = ctx.Committed()
+	})
+	e.GET("/stream", func(ctx *Context) {
+		ctx.Status(http.StatusCreated)
+		i := 0
+		ctx.Stream(func(w io.Writer) bool {
+			i++
+			_, _ = io.WriteString(w, strconv.Itoa(i))
+			return i < 3
+		})
+		// 提交后 RespData 不再输出
+		ctx.RespData = []byte("ignored")
+	})
+	e.GET("/direct", func(ctx *Context) {
+		ctx.Resp.Header().Set("Content-Type", "text/plain")
+		_, _ = ctx.Resp.Write([]byte("direct"))
+	})
+	e.GET("/buffered", func(ctx *Context) {
+		ctx.RespData = []byte("buffered")
+	})
+	e.GET("/wrapped", WrapF(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusPartialContent)
+		_, _ = w.Write([]byte("a"))
+		w.(http.Flusher).Flush()
+		_, _ = w.Write([]byte("b"))
+	}))
+
+	testCases := []struct {
+		path          string
+		wantStatus    int
+		wantBody      string
+		wantFlushed   bool
+		wantCommitted bool
+	}{
+		{path: "/stream", wantStatus: http.StatusCreated, wantBody: "123", wantFlushed: true, wantCommitted: true},
+		{path: "/direct", wantStatus: http.StatusOK, wantBody: "direct", wantCommitted: true},
+		{path: "/buffered", wantStatus: http.StatusOK, wantBody: "buffered", wantCommitted: false},
+		{path: "/wrapped", wantStatus: http.StatusPartialContent, wantBody: "ab", wantFlushed: true, wantCommitted: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			e.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			assert.Equal(t, tc.wantStatus, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+			assert.Equal(t, tc.wantFlushed, recorder.Flushed)
+			assert.Equal(t, tc.wantCommitted, committed)
+		})
+	}
+}
+
+func TestContext_Stream_clientGone(t *testing.T) {
+	reqCtx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(reqCtx)
+	ctx := newContext(httptest.NewRecorder(), req)
+
+	calls := 0
+	gone := ctx.Stream(func(w io.Writer) bool {
+		calls++
+		cancel()
+		return true
+	})
+	assert.True(t, gone)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, http.StatusOK, ctx.Writer().Status())
+}
@@ -1,169 +1,946 @@
 package web
 
-import "strings"
+import (
+	"maps"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
 
+// router 基于压缩前缀树(radix tree)的路由
+// 静态部分按字节压缩公共前缀，路径参数和通配符总是占据完整的一段路径
+//
+// 路由表是不可变的，修改时复制受影响的节点生成新的路由表再整体替换，
+// 因此可以在处理请求的同时注册、替换和删除路由，正在处理的请求仍使用旧的路由表
 type router struct {
+	// 当前生效的路由表，查找路由时直接读取，不需要加锁
+	table atomic.Pointer[routeTable]
+	// 串行化对路由表的修改
+	mu sync.Mutex
+	// 严格模式下路由和请求路径都按原样匹配，/users/ 和 /users 是不同的路由
+	strictSlash bool
+}
+
+// routeTable 某一时刻的完整路由表，发布之后不再修改
+type routeTable struct {
+	// 没有指定 host 的路由
+	trees methodTrees
+	// 静态 host -> 路由，如 api.example.com
+	exactHosts map[string]methodTrees
+	// 带参数的 host，按注册顺序匹配，优先级低于静态 host
+	paramHosts []*hostRouter
+	// 已注册路由中 host 参数和路径参数的最大数量，用于预分配 Params
+	// 删除路由时不会减小
+	maxParams int
+	// 路由名 -> 路由
+	names map[string]routeKey
+}
+
+// methodTrees method -> tree root
+type methodTrees map[string]*node
 
-	// method -> tree root
-	trees map[string]*node
+// hostRouter 带参数的 host 下的路由，如 :tenant.example.com
+type hostRouter struct {
+	pattern string
+	// 按 . 切分后的 host，每一段用 node 表示，:name 形式的部分为参数
+	labels []*node
+	trees  methodTrees
+}
+
+type routeKey struct {
+	host   string
+	method string
+	route  string
 }
 
 type node struct {
+	// 静态节点为压缩后的公共前缀，其余节点为完整的路径段，如 :id<int>、*filepath
 	path string
 	// 注册的路由字符串
-	route    string
+	route string
+	// 静态子节点 path 的首字节，和 children 一一对应
+	indices  string
 	children []*node
 	// 通配符
 	starChild  *node
 	paramChild *node
-	handlers   []HandleFunc
+	// 带约束的路径参数节点，如 :id<int>、:id(^\d+$)，匹配时优先于 paramChild
+	constrainedChildren []*node
+	// 末尾的 *name，匹配剩余的全部路径
+	catchAllChild *node
+	handlers      []HandleFunc
+	// 路径参数名
+	paramName string
+	// 路径参数约束，为 nil 时匹配任意路径段
+	constraint *regexp.Regexp
+	// 不参与 OPTIONS 自动应答
+	noAutoOptions bool
+	// 不为 nil 且返回 true 时，该路由不参与请求路径 path 的 405 判断
+	skipAllow func(path string) bool
+	// handlers 中来自分组中间件的数量
+	middlewares int
+	// 路由元数据，发布后不再修改
+	meta map[any]any
+}
+
+// routeEntry 注册路由时写入节点的内容
+type routeEntry struct {
+	handlers      []HandleFunc
+	middlewares   int
+	noAutoOptions bool
+	skipAllow     func(path string) bool
+	// 和路由在同一次更新中发布的元数据
+	meta map[any]any
+	// 路由已存在时替换而不是 panic
+	replace bool
+}
+
+// Param 一个路径参数
+type Param struct {
+	Key   string
+	Value string
+}
+
+// Params 按路由中出现的顺序保存路径参数
+type Params []Param
+
+// Get 返回第一个名为 key 的路径参数
+func (ps Params) Get(key string) (string, bool) {
+	for _, p := range ps {
+		if p.Key == key {
+			return p.Value, true
+		}
+	}
+	return "", false
 }
 
 type matchInfo struct {
 	node       *node
-	pathParams map[string]string
+	pathParams Params
 }
 
 func newRouter() *router {
-	return &router{
-		trees: make(map[string]*node),
+	r := &router{}
+	r.table.Store(&routeTable{
+		trees:      make(methodTrees),
+		exactHosts: make(map[string]methodTrees),
+		names:      make(map[string]routeKey),
+	})
+	return r
+}
+
+// load 返回当前的路由表，同一次请求中应该只读取一次
+func (r *router) load() *routeTable {
+	return r.table.Load()
+}
+
+// update 在当前路由表的副本上执行 fn，fn 返回 true 时用副本替换当前的路由表
+// fn panic 时当前的路由表保持不变
+func (r *router) update(fn func(t *routeTable) bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t := r.table.Load().clone()
+	if fn(t) {
+		r.table.Store(t)
 	}
 }
 
+// clone 复制路由表本身和各个 host 的索引，路由树和 names 仍然共用，修改前需要再复制
+func (t *routeTable) clone() *routeTable {
+	res := *t
+	res.trees = maps.Clone(t.trees)
+	res.exactHosts = maps.Clone(t.exactHosts)
+	res.paramHosts = slices.Clone(t.paramHosts)
+	return &res
+}
+
 // addRoute 注册路由
 // - 不能同时注册多个相同的路由
 // - 不能在同一个位置同时有通配符和路径参数
+// - *name 只能出现在最后一段
+// - 同一位置可以有多个带约束的路径参数，但只能有一个不带约束的路径参数
 func (r *router) addRoute(method string, path string, handlers ...HandleFunc) {
-	root, ok := r.trees[method]
-	if !ok {
-		root = &node{
-			path: "/",
+	r.addHostRoute("", method, path, routeEntry{handlers: handlers})
+}
+
+// addHostRoute 注册只匹配 host 的路由，host 为空时匹配任意 host
+func (r *router) addHostRoute(host string, method string, path string, entry routeEntry) {
+	tokens, paramCount := r.routeTokens(path)
+	r.update(func(t *routeTable) bool {
+		trees, hostParams := t.hostTrees(host, true)
+		root, ok := trees[method]
+		if !ok {
+			root = &node{
+				path: "/",
+			}
 		}
-		r.trees[method] = root
-	}
+		trees[method] = root.insert(tokens, func(n *node) {
+			if n.handlers != nil && !entry.replace {
+				panic("duplicated path")
+			}
+			if n.handlers == nil {
+				n.route = path
+			}
+			n.handlers = entry.handlers
+			n.middlewares = entry.middlewares
+			n.noAutoOptions = entry.noAutoOptions
+			n.skipAllow = entry.skipAllow
+			// 替换处理链时保留已有的元数据
+			if len(entry.meta) > 0 {
+				n.meta = mergeMeta(n.meta, entry.meta)
+			}
+		})
+		t.maxParams = max(t.maxParams, hostParams+paramCount)
+		return true
+	})
+}
 
-	segs := strings.Split(path, "/")
-	for _, seg := range segs {
-		if seg == "" {
+// removeRoute 删除路由以及指向它的路由名，路由不存在时返回 false
+func (r *router) removeRoute(host string, method string, path string) bool {
+	tokens, _ := r.routeTokens(path)
+	removed := false
+	r.update(func(t *routeTable) bool {
+		trees, _ := t.hostTrees(host, false)
+		root, ok := trees[method]
+		if !ok {
+			return false
+		}
+		root, old := root.remove(tokens)
+		if old == nil {
+			return false
+		}
+		if root.empty() {
+			delete(trees, method)
+		} else {
+			trees[method] = root
+		}
+		if len(trees) == 0 && host != "" {
+			t.removeHost(host)
+		}
+
+		key := routeKey{host: host, method: method, route: old.route}
+		for name, k := range t.names {
+			if k == key {
+				t.names = maps.Clone(t.names)
+				delete(t.names, name)
+				break
+			}
+		}
+		removed = true
+		return true
+	})
+	return removed
+}
+
+// setMeta 在同一次更新中设置多个已注册路由的元数据
+func (r *router) setMeta(keys []routeKey, name any, val any) {
+	r.update(func(t *routeTable) bool {
+		for _, key := range keys {
+			tokens, _ := r.routeTokens(key.route)
+			trees, _ := t.hostTrees(key.host, false)
+			root, ok := trees[key.method]
+			if !ok {
+				panic("route not found: " + key.route)
+			}
+			trees[key.method] = root.insert(tokens, func(n *node) {
+				if n.handlers == nil {
+					panic("route not found: " + key.route)
+				}
+				n.meta = mergeMeta(n.meta, map[any]any{name: val})
+			})
+		}
+		return true
+	})
+}
+
+// mergeMeta 返回合并后的新 map，已发布的节点的 meta 不能原地修改
+func mergeMeta(old map[any]any, meta map[any]any) map[any]any {
+	res := make(map[any]any, len(old)+len(meta))
+	maps.Copy(res, old)
+	maps.Copy(res, meta)
+	return res
+}
+
+// routeTokens 把路由切分为插入路由树的片段，连续的静态路径段合并为一个片段，路径参数和通配符各自单独作为一个片段
+// 如 /users/:id/orders 切分为 users/、:id、/orders，第二个返回值是路径参数的数量
+func (r *router) routeTokens(path string) ([]string, int) {
+	segs := splitPath(path)
+	tokens := make([]string, 0, len(segs))
+	static := ""
+	paramCount := 0
+	for i, seg := range segs {
+		if i > 0 {
+			static += "/"
+		}
+		if seg[0] != ':' && seg[0] != '*' {
+			static += seg
 			continue
 		}
+		if isCatchAll(seg) && i != len(segs)-1 {
+			panic("catch-all wildcard must be the last segment")
+		}
+		if static != "" {
+			tokens = append(tokens, static)
+			static = ""
+		}
+		tokens = append(tokens, seg)
+		if seg != "*" {
+			paramCount++
+		}
+	}
+	if r.strictSlash && len(segs) > 0 && strings.HasSuffix(path, "/") && !isCatchAll(segs[len(segs)-1]) {
+		// 严格模式下保留末尾的 /
+		static += "/"
+	}
+	if static != "" {
+		tokens = append(tokens, static)
+	}
+	return tokens, paramCount
+}
 
-		child := root.childOrCreate(seg)
+// hostTrees 复制 host 对应的路由树索引并写回 t，返回复制后的索引和 host 中参数的数量
+// host 未注册时，create 为 true 则创建，否则返回 nil
+func (t *routeTable) hostTrees(host string, create bool) (methodTrees, int) {
+	if host == "" {
+		return t.trees, 0
+	}
+	host = strings.ToLower(host)
+	labels := strings.Split(host, ".")
+	paramCount := 0
+	for _, label := range labels {
+		if label == "" {
+			panic("invalid host: " + host)
+		}
+		if label[0] == ':' {
+			paramCount++
+		}
+	}
 
-		root = child
+	if paramCount == 0 {
+		trees, ok := t.exactHosts[host]
+		if !ok && !create {
+			return nil, 0
+		}
+		trees = maps.Clone(trees)
+		if trees == nil {
+			trees = make(methodTrees)
+		}
+		t.exactHosts[host] = trees
+		return trees, 0
+	}
+
+	for i, h := range t.paramHosts {
+		if h.pattern == host {
+			c := *h
+			c.trees = maps.Clone(h.trees)
+			t.paramHosts[i] = &c
+			return c.trees, paramCount
+		}
+	}
+	if !create {
+		return nil, 0
+	}
+	h := &hostRouter{
+		pattern: host,
+		labels:  make([]*node, 0, len(labels)),
+		trees:   make(methodTrees),
+	}
+	for _, label := range labels {
+		n := &node{
+			path: label,
+		}
+		if label[0] == ':' {
+			n.paramName, n.constraint = parseParam(label)
+		}
+		h.labels = append(h.labels, n)
+	}
+	t.paramHosts = append(t.paramHosts, h)
+	return h.trees, paramCount
+}
+
+// removeHost 删除 host，此后该 host 的请求使用没有指定 host 的路由
+func (t *routeTable) removeHost(host string) {
+	host = strings.ToLower(host)
+	delete(t.exactHosts, host)
+	t.paramHosts = slices.DeleteFunc(t.paramHosts, func(h *hostRouter) bool {
+		return h.pattern == host
+	})
+}
+
+// selectTrees 根据请求的 host 选出路由树，host 中的参数追加到 params
+// 静态 host 优先，其次按注册顺序匹配带参数的 host，都不匹配时使用没有指定 host 的路由
+func (t *routeTable) selectTrees(host string, params Params) (methodTrees, Params) {
+	if len(t.exactHosts) == 0 && len(t.paramHosts) == 0 {
+		return t.trees, params
+	}
+	host = strings.ToLower(stripPort(host))
+	if trees, ok := t.exactHosts[host]; ok {
+		return trees, params
+	}
+	for _, h := range t.paramHosts {
+		if res, ok := h.match(host, params); ok {
+			return h.trees, res
+		}
+	}
+	return t.trees, params
+}
+
+// match 逐段匹配 host，参数追加到 params
+func (h *hostRouter) match(host string, params Params) (Params, bool) {
+	mark := len(params)
+	for i, label := range h.labels {
+		end := strings.IndexByte(host, '.')
+		if i == len(h.labels)-1 {
+			// 最后一段不能再包含 .
+			if end >= 0 {
+				return params[:mark], false
+			}
+			end = len(host)
+		} else if end < 0 {
+			return params[:mark], false
+		}
+
+		seg := host[:end]
+		if label.path[0] == ':' {
+			if seg == "" || label.constraint != nil && !label.constraint.MatchString(seg) {
+				return params[:mark], false
+			}
+			params = append(params, Param{Key: label.paramName, Value: seg})
+		} else if seg != label.path {
+			return params[:mark], false
+		}
+		if end < len(host) {
+			host = host[end+1:]
+		}
+	}
+	return params, true
+}
+
+// stripPort 去掉 host 中的端口，兼容 [::1]:8080 形式的 IPv6 地址
+func stripPort(host string) string {
+	i := strings.LastIndexByte(host, ':')
+	if i < 0 || strings.IndexByte(host[i:], ']') >= 0 {
+		return host
 	}
+	return host[:i]
+}
 
-	if root.handlers != nil {
-		panic("duplicated path")
+// stripRegisteredPort 去掉注册的 host 中的端口，host 参数同样以 : 开头，只去掉数字组成的端口
+func stripRegisteredPort(host string) string {
+	i := strings.LastIndexByte(host, ':')
+	if i <= 0 || i == len(host)-1 || strings.Trim(host[i+1:], "0123456789") != "" {
+		return host
 	}
-	root.route = path
-	root.handlers = append(root.handlers, handlers...)
+	return host[:i]
+}
+
+// nameRoute 给路由命名，同一个名字只能使用一次
+func (r *router) nameRoute(name string, key routeKey) {
+	r.update(func(t *routeTable) bool {
+		if _, ok := t.names[name]; ok {
+			panic("duplicated route name: " + name)
+		}
+		t.names = maps.Clone(t.names)
+		t.names[name] = key
+		return true
+	})
+}
+
+// findRoute 查找路由，匹配到的路径参数追加到 params 后返回
+// 静态路由和路径参数路由在 params 容量足够时不会产生内存分配
+func (r *router) findRoute(method string, path string, params Params) (matchInfo, bool) {
+	return r.findRouteIn(r.load().trees, method, path, params)
+}
+
+// findHostRoute 先根据 host 选出路由树再查找路由，host 参数位于路径参数之前
+func (r *router) findHostRoute(host string, method string, path string, params Params) (matchInfo, bool) {
+	trees, params := r.load().selectTrees(host, params)
+	return r.findRouteIn(trees, method, path, params)
 }
 
-func (r *router) findRoute(method string, path string) (*matchInfo, bool) {
-	root, ok := r.trees[method]
+func (r *router) findRouteIn(trees methodTrees, method string, path string, params Params) (matchInfo, bool) {
+	root, ok := trees[method]
 	if !ok {
-		return nil, false
+		return matchInfo{}, false
+	}
+
+	if !r.strictSlash {
+		path = cleanSegments(path)
+	} else if path == "" || path[0] != '/' {
+		return matchInfo{}, false
+	}
+	n, ok := root.match(path[1:], &params)
+	if !ok {
+		return matchInfo{}, false
+	}
+	return matchInfo{
+		node:       n,
+		pathParams: params,
+	}, true
+}
+
+// findFoldPath 忽略静态部分的大小写查找路由，返回按注册时大小写修正后的路径
+func (r *router) findFoldPath(host string, method string, path string) (string, bool) {
+	trees, _ := r.load().selectTrees(host, nil)
+	root, ok := trees[method]
+	if !ok {
+		return "", false
+	}
+
+	if !r.strictSlash {
+		path = cleanSegments(path)
+	} else if path == "" || path[0] != '/' {
+		return "", false
+	}
+	buf := make([]byte, 1, len(path))
+	buf[0] = '/'
+	res, ok := root.matchFold(path[1:], buf)
+	if !ok {
+		return "", false
 	}
+	return string(res), true
+}
 
-	segs := strings.Split(path, "/")
-	pathParams := make(map[string]string, 2)
-	for _, seg := range segs {
+// allowedMethods 返回所有能匹配 path 的http method，按字典序排列
+// 第二个返回值表示匹配的路由中是否有允许自动应答 OPTIONS 的
+func (r *router) allowedMethods(host string, path string) ([]string, bool) {
+	var res []string
+	autoOptions := false
+	trees, _ := r.load().selectTrees(host, nil)
+	for method := range trees {
+		info, ok := r.findRouteIn(trees, method, path, nil)
+		if ok && (info.node.skipAllow == nil || !info.node.skipAllow(path)) {
+			res = append(res, method)
+			autoOptions = autoOptions || !info.node.noAutoOptions
+		}
+	}
+	sort.Strings(res)
+	return res, autoOptions
+}
+
+// splitPath 按 / 切分路径，忽略空的路径段
+func splitPath(path string) []string {
+	segs := make([]string, 0, strings.Count(path, "/")+1)
+	for _, seg := range strings.Split(path, "/") {
 		if seg == "" {
 			continue
 		}
+		segs = append(segs, seg)
+	}
+	return segs
+}
 
-		child, isParam, ok := root.childOf(seg)
-		if !ok {
-			return nil, false
+// cleanSegments 去掉路径中的空路径段，返回以 / 开头的路径
+// 路径本身已经符合要求时直接返回，不产生内存分配
+func cleanSegments(path string) string {
+	if path == "/" {
+		return path
+	}
+	if path != "" && path[0] == '/' && path[len(path)-1] != '/' && !strings.Contains(path, "//") {
+		return path
+	}
+	return "/" + strings.Join(splitPath(path), "/")
+}
+
+// cleanPath 清理路径中的 .、.. 和重复的 /，保留末尾的 /
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	res := path.Clean("/" + p)
+	if p[len(p)-1] == '/' && res != "/" {
+		res += "/"
+	}
+	return res
+}
+
+// isCatchAll 判断路径段是否是 *name 形式的通配符，单独的 * 只匹配一段路径
+func isCatchAll(seg string) bool {
+	return len(seg) > 1 && seg[0] == '*'
+}
+
+// match 匹配 n 之后剩余的路径，只有匹配到注册了 handlers 的节点才算成功
+// 优先级为 静态节点 > 带约束的路径参数节点 > 路径参数节点 > 通配符节点 > *name，深层匹配失败时回溯尝试下一种节点
+func (n *node) match(path string, params *Params) (*node, bool) {
+	if path == "" {
+		if n.handlers != nil {
+			return n, true
 		}
-		if isParam {
-			pathParams[child.path[1:]] = seg
+		if n.catchAllChild != nil {
+			// *name 可以匹配空路径
+			*params = append(*params, Param{Key: n.catchAllChild.paramName})
+			return n.catchAllChild, true
 		}
+		if i := strings.IndexByte(n.indices, '/'); i >= 0 {
+			return n.children[i].matchEmptyCatchAll("", params)
+		}
+		return nil, false
+	}
 
-		root = child
+	if i := strings.IndexByte(n.indices, path[0]); i >= 0 {
+		child := n.children[i]
+		if strings.HasPrefix(path, child.path) {
+			if res, ok := child.match(path[len(child.path):], params); ok {
+				return res, true
+			}
+		} else if res, ok := child.matchEmptyCatchAll(path, params); ok {
+			return res, true
+		}
+	}
 
+	if n.paramChild == nil && n.constrainedChildren == nil && n.starChild == nil && n.catchAllChild == nil {
+		return nil, false
 	}
 
-	return &matchInfo{
-		node:       root,
-		pathParams: pathParams,
-	}, true
+	// 有路径参数或通配符子节点的节点 path 一定以 / 结尾，此时 path 处于一段路径的开头
+	end := strings.IndexByte(path, '/')
+	if end < 0 {
+		end = len(path)
+	}
+	seg, rest := path[:end], path[end:]
+	mark := len(*params)
+
+	// 路径参数和 * 不匹配空的路径段，否则严格模式下 /:a/:b 会匹配 //evil.com
+	if seg == "" {
+		if n.catchAllChild != nil {
+			*params = append(*params, Param{Key: n.catchAllChild.paramName, Value: path})
+			return n.catchAllChild, true
+		}
+		return nil, false
+	}
+
+	for _, child := range n.constrainedChildren {
+		if !child.constraint.MatchString(seg) {
+			continue
+		}
+		*params = append(*params, Param{Key: child.paramName, Value: seg})
+		if res, ok := child.match(rest, params); ok {
+			return res, true
+		}
+		// 回溯时丢弃该分支记录的参数
+		*params = (*params)[:mark]
+	}
+
+	if n.paramChild != nil {
+		*params = append(*params, Param{Key: n.paramChild.paramName, Value: seg})
+		if res, ok := n.paramChild.match(rest, params); ok {
+			return res, true
+		}
+		*params = (*params)[:mark]
+	}
+
+	if n.starChild != nil {
+		if res, ok := n.starChild.match(rest, params); ok {
+			return res, true
+		}
+	}
+
+	if n.catchAllChild != nil {
+		*params = append(*params, Param{Key: n.catchAllChild.paramName, Value: path})
+		return n.catchAllChild, true
+	}
+	return nil, false
 }
 
-func (n *node) childOrCreate(seg string) *node {
-	if seg == "*" {
-		if n.paramChild != nil {
-			panic("can't register wildcard and param node at the same time")
+// matchEmptyCatchAll 处理剩余路径只差末尾的 / 就能到达 *name 的情况，如 /static 匹配 /static/*filepath
+func (n *node) matchEmptyCatchAll(path string, params *Params) (*node, bool) {
+	if n.catchAllChild == nil || len(n.path) != len(path)+1 ||
+		n.path[len(path)] != '/' || !strings.HasPrefix(n.path, path) {
+		return nil, false
+	}
+	*params = append(*params, Param{Key: n.catchAllChild.paramName})
+	return n.catchAllChild, true
+}
+
+// matchFold 和 match 的匹配规则相同，但静态部分忽略大小写，匹配成功时把修正后的路径追加到 buf 返回
+// 只在找不到路由时用于重定向，不追求性能
+func (n *node) matchFold(path string, buf []byte) ([]byte, bool) {
+	if path == "" {
+		if n.handlers != nil || n.catchAllChild != nil {
+			return buf, true
 		}
+		if i := strings.IndexByte(n.indices, '/'); i >= 0 && n.children[i].path == "/" && n.children[i].catchAllChild != nil {
+			return buf, true
+		}
+		return nil, false
 	}
 
-	if seg[0] == ':' {
-		if n.starChild != nil {
-			panic("can't register wildcard and param node at the same time")
+	for _, child := range n.children {
+		if len(path) >= len(child.path) && strings.EqualFold(path[:len(child.path)], child.path) {
+			if res, ok := child.matchFold(path[len(child.path):], append(buf, child.path...)); ok {
+				return res, true
+			}
+		} else if child.catchAllChild != nil && len(child.path) == len(path)+1 &&
+			child.path[len(path)] == '/' && strings.EqualFold(child.path[:len(path)], path) {
+			return append(buf, child.path[:len(path)]...), true
 		}
-		if n.paramChild != nil {
-			panic("can't register two param nodes at the same level")
+	}
+
+	if n.paramChild == nil && n.constrainedChildren == nil && n.starChild == nil && n.catchAllChild == nil {
+		return nil, false
+	}
+
+	end := strings.IndexByte(path, '/')
+	if end < 0 {
+		end = len(path)
+	}
+	seg, rest := path[:end], path[end:]
+	if seg == "" {
+		if n.catchAllChild != nil {
+			return append(buf, path...), true
 		}
+		return nil, false
 	}
 
-	child, _, ok := n.childOf(seg)
-	if !ok {
-		child = &node{
-			path: seg,
+	for _, child := range n.constrainedChildren {
+		if !child.constraint.MatchString(seg) {
+			continue
 		}
-		if seg == "*" {
-			n.starChild = child
-		} else if seg[0] == ':' {
-			n.paramChild = child
-		} else {
-			n.children = append(n.children, child)
+		if res, ok := child.matchFold(rest, append(buf, seg...)); ok {
+			return res, true
 		}
-	} else if child.path == "*" {
-		// 匹配到通配符
-		if seg == "*" {
-			// 自己也是通配符，不需要创建新的节点
-			return child
-		} else {
-			// 自己是详细路径，创建一个新的节点
+	}
+
+	for _, child := range []*node{n.paramChild, n.starChild} {
+		if child == nil {
+			continue
+		}
+		if res, ok := child.matchFold(rest, append(buf, seg...)); ok {
+			return res, true
+		}
+	}
+
+	if n.catchAllChild != nil {
+		return append(buf, path...), true
+	}
+	return nil, false
+}
+
+func commonPrefixLen(a, b string) int {
+	l := min(len(a), len(b))
+	for i := 0; i < l; i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return l
+}
+
+// insert 返回插入 tokens 后的新节点，沿途经过的节点都会被复制，n 本身不会被修改
+// set 用于写入 tokens 末尾对应的节点
+func (n *node) insert(tokens []string, set func(n *node)) *node {
+	c := *n
+	switch {
+	case len(tokens) == 0:
+		set(&c)
+	case tokens[0][0] == ':' || tokens[0][0] == '*':
+		c.insertDynamic(tokens[0], tokens[1:], set)
+	default:
+		c.insertStatic(tokens[0], tokens[1:], set)
+	}
+	return &c
+}
+
+// insertStatic 在 n 下插入静态路径，必要时拆分已有节点，n 必须是已经复制过的节点
+func (n *node) insertStatic(path string, tokens []string, set func(n *node)) {
+	i := strings.IndexByte(n.indices, path[0])
+	if i < 0 {
+		child := &node{
+			path: path,
+		}
+		n.indices += path[:1]
+		n.children = append(slices.Clip(n.children), child.insert(tokens, set))
+		return
+	}
+
+	child := n.children[i]
+	l := commonPrefixLen(child.path, path)
+	if l < len(child.path) {
+		child = child.split(l)
+	}
+	if l == len(path) {
+		child = child.insert(tokens, set)
+	} else {
+		c := *child
+		c.insertStatic(path[l:], tokens, set)
+		child = &c
+	}
+	n.children = slices.Clone(n.children)
+	n.children[i] = child
+}
+
+// split 返回把节点拆分为 path[:i] 和 path[i:] 两个节点后的前者，原有的子节点和 handlers 都归属于后者
+func (n *node) split(i int) *node {
+	suffix := *n
+	suffix.path = n.path[i:]
+	return &node{
+		path:     n.path[:i],
+		indices:  suffix.path[:1],
+		children: []*node{&suffix},
+	}
+}
+
+// insertDynamic 在 n 下插入路径参数、通配符节点，n 必须是已经复制过的节点
+func (n *node) insertDynamic(seg string, tokens []string, set func(n *node)) {
+	if isCatchAll(seg) {
+		child := n.catchAllChild
+		if child == nil {
+			child = &node{
+				path:      seg,
+				paramName: seg[1:],
+			}
+		} else if child.path != seg {
+			panic("can't register two catch-all nodes at the same level")
+		}
+		n.catchAllChild = child.insert(tokens, set)
+		return
+	}
+
+	if seg == "*" {
+		if n.paramChild != nil || len(n.constrainedChildren) > 0 {
+			panic("can't register wildcard and param node at the same time")
+		}
+		child := n.starChild
+		if child == nil {
 			child = &node{
 				path: seg,
 			}
-			n.children = append(n.children, child)
 		}
-	} else if child.path[0] == ':' {
-		// 已有参数节点，自己是详细路径，创建一个新的节点
-		child = &node{
-			path: seg,
+		n.starChild = child.insert(tokens, set)
+		return
+	}
+
+	if n.starChild != nil {
+		panic("can't register wildcard and param node at the same time")
+	}
+	name, constraint := parseParam(seg)
+	if constraint == nil {
+		child := n.paramChild
+		if child == nil {
+			child = &node{
+				path:      seg,
+				paramName: name,
+			}
+		} else if child.path != seg {
+			panic("can't register two param nodes at the same level")
 		}
-		n.children = append(n.children, child)
+		n.paramChild = child.insert(tokens, set)
+		return
 	}
-	return child
-}
 
-// childOf returns the child node of n with path seg
-// 第一个返回值是找到的节点
-// 第二个返回值是找到的节点是否是路径参数节点
-// 第三个返回值是否找到了节点
-func (n *node) childOf(seg string) (*node, bool, bool) {
-	if n.children == nil {
+	children := slices.Clone(n.constrainedChildren)
+	i := slices.IndexFunc(children, func(child *node) bool {
+		return child.path == seg
+	})
+	if i < 0 {
+		children = append(children, &node{
+			path:       seg,
+			paramName:  name,
+			constraint: constraint,
+		})
+		i = len(children) - 1
+	}
+	children[i] = children[i].insert(tokens, set)
+	n.constrainedChildren = children
+}
 
-		if n.paramChild != nil {
-			return n.paramChild, true, true
+// remove 返回删除 tokens 对应路由后的新节点和被删除的路由节点，没有注册该路由时后者为 nil
+// 删除后没有 handlers 和子节点的节点会一并删除，但不会合并之前拆分的静态节点
+func (n *node) remove(tokens []string) (*node, *node) {
+	switch {
+	case len(tokens) == 0:
+		if n.handlers == nil {
+			return n, nil
 		}
+		c := *n
+		c.route, c.handlers, c.middlewares, c.noAutoOptions, c.skipAllow, c.meta = "", nil, 0, false, nil, nil
+		return &c, n
+	case tokens[0][0] == ':' || tokens[0][0] == '*':
+		return n.removeDynamic(tokens[0], tokens[1:])
+	default:
+		return n.removeStatic(tokens[0], tokens[1:])
+	}
+}
 
-		return n.starChild, false, n.starChild != nil
+func (n *node) removeStatic(path string, tokens []string) (*node, *node) {
+	i := strings.IndexByte(n.indices, path[0])
+	if i < 0 || !strings.HasPrefix(path, n.children[i].path) {
+		return n, nil
+	}
+	child, old := n.children[i], (*node)(nil)
+	if len(path) == len(child.path) {
+		child, old = child.remove(tokens)
+	} else {
+		child, old = child.removeStatic(path[len(child.path):], tokens)
+	}
+	if old == nil {
+		return n, nil
 	}
 
-	for _, child := range n.children {
-		if child.path == seg {
-			return child, false, true
+	c := *n
+	c.children = slices.Clone(n.children)
+	if child.empty() {
+		c.indices = c.indices[:i] + c.indices[i+1:]
+		c.children = slices.Delete(c.children, i, i+1)
+	} else {
+		c.children[i] = child
+	}
+	return &c, old
+}
+
+func (n *node) removeDynamic(seg string, tokens []string) (*node, *node) {
+	c := *n
+	var slot **node
+	switch {
+	case isCatchAll(seg):
+		slot = &c.catchAllChild
+	case seg == "*":
+		slot = &c.starChild
+	case n.paramChild != nil && n.paramChild.path == seg:
+		slot = &c.paramChild
+	default:
+		i := slices.IndexFunc(n.constrainedChildren, func(child *node) bool {
+			return child.path == seg
+		})
+		if i < 0 {
+			return n, nil
 		}
+		child, old := n.constrainedChildren[i].remove(tokens)
+		if old == nil {
+			return n, nil
+		}
+		c.constrainedChildren = slices.Clone(n.constrainedChildren)
+		if child.empty() {
+			c.constrainedChildren = slices.Delete(c.constrainedChildren, i, i+1)
+			if len(c.constrainedChildren) == 0 {
+				c.constrainedChildren = nil
+			}
+		} else {
+			c.constrainedChildren[i] = child
+		}
+		return &c, old
 	}
 
-	// 静态节点中没有找到，查找通配符节点
-	if n.paramChild != nil {
-		return n.paramChild, true, true
+	if *slot == nil || (*slot).path != seg {
+		return n, nil
+	}
+	child, old := (*slot).remove(tokens)
+	if old == nil {
+		return n, nil
 	}
-	return n.starChild, false, n.starChild != nil
+	if child.empty() {
+		child = nil
+	}
+	*slot = child
+	return &c, old
+}
+
+// empty 判断节点是否既没有 handlers 也没有任何子节点
+func (n *node) empty() bool {
+	return n.handlers == nil && len(n.children) == 0 && n.starChild == nil && n.paramChild == nil &&
+		len(n.constrainedChildren) == 0 && n.catchAllChild == nil
 }
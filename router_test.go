@@ -88,18 +88,21 @@ func TestRouter_addRouter(t *testing.T) {
 
 	r := newRouter()
 
-	wantRouter := &router{
+	// 静态部分压缩公共前缀，路径参数和通配符单独成为节点
+	wantRouter := &routeTable{
 		trees: map[string]*node{
 			http.MethodGet: {
 				path:     "/",
 				handlers: []HandleFunc{mockHandler},
+				indices:  "u",
 				children: []*node{
 					{
 						path:     "user",
 						handlers: []HandleFunc{mockHandler},
+						indices:  "/",
 						children: []*node{
 							{
-								path:     "home",
+								path:     "/home",
 								handlers: []HandleFunc{mockHandler},
 							},
 						},
@@ -110,36 +113,55 @@ func TestRouter_addRouter(t *testing.T) {
 			http.MethodPost: {
 				path:     "/",
 				handlers: []HandleFunc{mockHandler},
+				indices:  "o",
 				children: []*node{
-
 					{
-						path:     "order",
-						handlers: []HandleFunc{mockHandler},
-						starChild: &node{
-							path:     "*",
-							handlers: []HandleFunc{mockHandler},
-						},
+						path:    "or",
+						indices: "di",
 						children: []*node{
 							{
-								path:     "detail",
+								path:     "der",
 								handlers: []HandleFunc{mockHandler},
-								paramChild: &node{
-									path:     ":id",
-									handlers: []HandleFunc{mockHandler},
+								indices:  "/",
+								children: []*node{
+									{
+										path:    "/",
+										indices: "d",
+										starChild: &node{
+											path:     "*",
+											handlers: []HandleFunc{mockHandler},
+										},
+										children: []*node{
+											{
+												path:     "detail",
+												handlers: []HandleFunc{mockHandler},
+												indices:  "/1",
+												children: []*node{
+													{
+														path: "/",
+														paramChild: &node{
+															path:     ":id",
+															handlers: []HandleFunc{mockHandler},
+														},
+													},
+													{
+														path:     "1",
+														handlers: []HandleFunc{mockHandler},
+													},
+												},
+											},
+										},
+									},
 								},
 							},
 							{
-								path:     "detail1",
-								handlers: []HandleFunc{mockHandler},
+								path: "igin",
+								handlers: []HandleFunc{
+									mockHandler,
+								},
 							},
 						},
 					},
-					{
-						path: "origin",
-						handlers: []HandleFunc{
-							mockHandler,
-						},
-					},
 				},
 			},
 		},
@@ -148,7 +170,7 @@ func TestRouter_addRouter(t *testing.T) {
 		r.addRoute(route.method, route.path, route.handlers...)
 	}
 
-	if msg, equal := r.equal(wantRouter); !equal {
+	if msg, equal := r.load().equal(wantRouter); !equal {
 		t.Errorf("router 不相同: %s", msg)
 	}
 
@@ -179,6 +201,33 @@ func TestRouter_addRouter(t *testing.T) {
 	assert.Panicsf(t, func() {
 		r.addRoute(http.MethodGet, "/user/:name", mockHandler)
 	}, "can't register two param nodes at the same level")
+
+	// 未知的路径参数类型panic
+	r = newRouter()
+	assert.Panicsf(t, func() {
+		r.addRoute(http.MethodGet, "/user/:id<number>", mockHandler)
+	}, "unknown param type: number")
+
+	// 带约束的路径参数和通配符同时注册panic
+	r = newRouter()
+	r.addRoute(http.MethodGet, "/user/:id<int>", mockHandler)
+	assert.Panicsf(t, func() {
+		r.addRoute(http.MethodGet, "/user/*", mockHandler)
+	}, "can't register wildcard and param node at the same time")
+
+	// *name 不在最后一段panic
+	r = newRouter()
+	assert.Panicsf(t, func() {
+		r.addRoute(http.MethodGet, "/static/*filepath/detail", mockHandler)
+	}, "catch-all wildcard must be the last segment")
+
+	// 同一位置注册不同名的 *name panic
+	r = newRouter()
+	r.addRoute(http.MethodGet, "/static/*filepath", mockHandler)
+	assert.Panicsf(t, func() {
+		r.addRoute(http.MethodPost, "/static/*filepath", mockHandler)
+		r.addRoute(http.MethodGet, "/static/*name", mockHandler)
+	}, "can't register two catch-all nodes at the same level")
 }
 
 func (n *node) equal(y *node) (string, bool) {
@@ -194,6 +243,10 @@ func (n *node) equal(y *node) (string, bool) {
 		return "children 数量不相同", false
 	}
 
+	if n.indices != y.indices {
+		return "indices 不相同", false
+	}
+
 	if len(n.handlers) != len(y.handlers) {
 		return "handlers 数量不相同", false
 	}
@@ -212,6 +265,13 @@ func (n *node) equal(y *node) (string, bool) {
 		}
 	}
 
+	if n.catchAllChild != nil {
+		msg, equal := n.catchAllChild.equal(y.catchAllChild)
+		if !equal {
+			return msg, false
+		}
+	}
+
 	for i := 0; i < len(n.handlers); i++ {
 		nHandler := reflect.ValueOf(n.handlers[i])
 		yHandler := reflect.ValueOf(y.handlers[i])
@@ -229,8 +289,8 @@ func (n *node) equal(y *node) (string, bool) {
 	return "", true
 }
 
-func (r *router) equal(y *router) (string, bool) {
-	for k, v := range r.trees {
+func (t *routeTable) equal(y *routeTable) (string, bool) {
+	for k, v := range t.trees {
 		dst, ok := y.trees[k]
 		if !ok {
 			return "找不到对应的http method", false
@@ -336,24 +396,19 @@ func TestRouter_findRoute(t *testing.T) {
 	}
 
 	testCases := []struct {
-		name      string
-		method    string
-		path      string
-		wantFound bool
-		info      *matchInfo
+		name       string
+		method     string
+		path       string
+		wantFound  bool
+		wantRoute  string
+		wantParams Params
 	}{
 		{
 			name:      "order detail",
 			method:    http.MethodPost,
 			path:      "/order/detail",
 			wantFound: true,
-			info: &matchInfo{
-				node: &node{
-					path:     "detail",
-					handlers: []HandleFunc{mockHandler},
-				},
-				pathParams: map[string]string{},
-			},
+			wantRoute: "/order/detail",
 		},
 
 		{
@@ -361,58 +416,43 @@ func TestRouter_findRoute(t *testing.T) {
 			method:    http.MethodPost,
 			path:      "/order",
 			wantFound: true,
-			info: &matchInfo{
-				node: &node{
-					path:     "order",
-					handlers: []HandleFunc{mockHandler},
-					children: []*node{
-						{
-							path:     "detail",
-							handlers: []HandleFunc{mockHandler},
-						},
-					},
-				},
-				pathParams: map[string]string{},
-			},
+			wantRoute: "/order",
 		},
 		{
 			name:      "order *",
 			method:    http.MethodPost,
 			path:      "/order/123",
 			wantFound: true,
-			info: &matchInfo{
-				node: &node{
-					path:     "*",
-					handlers: []HandleFunc{mockHandler},
-				},
-				pathParams: map[string]string{},
-			},
+			wantRoute: "/order/*",
 		},
 		{
-			name:      "order detail :id",
-			method:    http.MethodPost,
-			path:      "/order/detail/123",
-			wantFound: true,
-			info: &matchInfo{
-				node: &node{
-					path:     ":id",
-					handlers: []HandleFunc{mockHandler},
-				},
-				pathParams: map[string]string{"id": "123"},
-			},
+			name:       "order detail :id",
+			method:     http.MethodPost,
+			path:       "/order/detail/123",
+			wantFound:  true,
+			wantRoute:  "/order/detail/:id",
+			wantParams: Params{{Key: "id", Value: "123"}},
 		},
 		{
 			name:      "post * detail",
 			method:    http.MethodPost,
 			path:      "/post/123/detail",
 			wantFound: true,
-			info: &matchInfo{
-				node: &node{
-					path:     "detail",
-					handlers: []HandleFunc{mockHandler},
-				},
-				pathParams: map[string]string{},
-			},
+			wantRoute: "/post/*/detail",
+		},
+		{
+			name:      "origin",
+			method:    http.MethodPost,
+			path:      "/origin",
+			wantFound: true,
+			wantRoute: "origin",
+		},
+		{
+			name:      "empty segments",
+			method:    http.MethodPost,
+			path:      "//order//detail/",
+			wantFound: true,
+			wantRoute: "/order/detail",
 		},
 
 		{
@@ -428,17 +468,474 @@ func TestRouter_findRoute(t *testing.T) {
 			path:      "/order/123/x",
 			wantFound: false,
 		},
+		{
+			name:      "prefix of static node",
+			method:    http.MethodPost,
+			path:      "/ord",
+			wantFound: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			info, found := r.findRoute(tc.method, tc.path, nil)
+			assert.Equal(t, tc.wantFound, found)
+			if !found {
+				return
+			}
+			assert.Equal(t, tc.wantRoute, info.node.route)
+			assert.Equal(t, tc.wantParams, info.pathParams)
+		})
+	}
+}
+
+func TestRouter_findRoute_backtracking(t *testing.T) {
+	r := newRouter()
+	routes := []string{
+		"/a/b/c",
+		"/a/:id/d",
+		"/a/b",
+		"/x/k/:q/w",
+		"/x/:p/y/z",
+		"/s/b/c",
+		"/s/*/e",
+		"/m/n/o/p",
+		"/m/:id",
+	}
+	for _, route := range routes {
+		r.addRoute(http.MethodGet, route, mockHandler)
+	}
+
+	testCases := []struct {
+		name       string
+		path       string
+		wantFound  bool
+		wantRoute  string
+		wantParams Params
+	}{
+		{
+			name:      "static first",
+			path:      "/a/b/c",
+			wantFound: true,
+			wantRoute: "/a/b/c",
+		},
+		{
+			name:       "static fails, fall back to param",
+			path:       "/a/b/d",
+			wantFound:  true,
+			wantRoute:  "/a/:id/d",
+			wantParams: Params{{Key: "id", Value: "b"}},
+		},
+		{
+			name:       "static node without handlers",
+			path:       "/m/n",
+			wantFound:  true,
+			wantRoute:  "/m/:id",
+			wantParams: Params{{Key: "id", Value: "n"}},
+		},
+		{
+			name:       "backtrack across params",
+			path:       "/x/k/y/z",
+			wantFound:  true,
+			wantRoute:  "/x/:p/y/z",
+			wantParams: Params{{Key: "p", Value: "k"}},
+		},
+		{
+			name:       "deeper param matched",
+			path:       "/x/k/v/w",
+			wantFound:  true,
+			wantRoute:  "/x/k/:q/w",
+			wantParams: Params{{Key: "q", Value: "v"}},
+		},
+		{
+			name:      "static fails, fall back to wildcard",
+			path:      "/s/b/e",
+			wantFound: true,
+			wantRoute: "/s/*/e",
+		},
+		{
+			name:      "all branches fail",
+			path:      "/a/b/e",
+			wantFound: false,
+		},
+		{
+			name:      "too deep",
+			path:      "/m/n/o",
+			wantFound: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			info, found := r.findRoute(http.MethodGet, tc.path, nil)
+			assert.Equal(t, tc.wantFound, found)
+			if !found {
+				return
+			}
+			assert.Equal(t, tc.wantRoute, info.node.route)
+			assert.Equal(t, tc.wantParams, info.pathParams)
+		})
+	}
+}
+
+func TestRouter_removeRoute(t *testing.T) {
+	r := newRouter()
+	routes := []string{
+		"/user",
+		"/user/home",
+		"/user/:id",
+		"/user/:id<int>/orders",
+		"/static/*filepath",
+		"/post/*/comments",
+	}
+	for _, route := range routes {
+		r.addRoute(http.MethodGet, route, mockHandler)
+	}
+	r.addHostRoute("api.example.com", http.MethodGet, "/user", routeEntry{handlers: []HandleFunc{mockHandler}})
+	r.nameRoute("user.show", routeKey{method: http.MethodGet, route: "/user/:id"})
+	old := r.load()
+
+	assert.True(t, r.removeRoute("", http.MethodGet, "/user/:id"))
+	assert.True(t, r.removeRoute("", http.MethodGet, "/user/home"))
+	assert.True(t, r.removeRoute("", http.MethodGet, "/static/*filepath"))
+	assert.True(t, r.removeRoute("api.example.com", http.MethodGet, "/user"))
+	// 不存在的路由
+	assert.False(t, r.removeRoute("", http.MethodGet, "/user/:id"))
+	assert.False(t, r.removeRoute("", http.MethodGet, "/user/:name"))
+	assert.False(t, r.removeRoute("", http.MethodGet, "/use"))
+	assert.False(t, r.removeRoute("", http.MethodPost, "/user"))
+	assert.False(t, r.removeRoute("www.example.com", http.MethodGet, "/user"))
+
+	testCases := []struct {
+		host      string
+		path      string
+		wantRoute string
+	}{
+		{path: "/user", wantRoute: "/user"},
+		{path: "/user/home"},
+		{path: "/user/abc"},
+		{path: "/user/123/orders", wantRoute: "/user/:id<int>/orders"},
+		{path: "/static/app.js"},
+		{path: "/post/1/comments", wantRoute: "/post/*/comments"},
+		// host 的路由全部删除后使用没有指定 host 的路由
+		{host: "api.example.com", path: "/user", wantRoute: "/user"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.host+tc.path, func(t *testing.T) {
+			info, found := r.findHostRoute(tc.host, http.MethodGet, tc.path, nil)
+			assert.Equal(t, tc.wantRoute != "", found)
+			if found {
+				assert.Equal(t, tc.wantRoute, info.node.route)
+			}
+		})
+	}
+
+	// 删除路由不影响之前的路由表
+	info, found := r.findRouteIn(old.trees, http.MethodGet, "/user/abc", nil)
+	assert.True(t, found)
+	assert.Equal(t, "/user/:id", info.node.route)
+	assert.Len(t, old.exactHosts, 1)
+
+	// 指向被删除路由的路由名一并删除
+	_, ok := r.load().names["user.show"]
+	assert.False(t, ok)
+
+	// 删除的路径参数节点不再阻止注册同一位置的路径参数
+	r.addRoute(http.MethodGet, "/user/:name", mockHandler)
+	info, found = r.findRoute(http.MethodGet, "/user/abc", nil)
+	assert.True(t, found)
+	assert.Equal(t, "/user/:name", info.node.route)
+
+	// 删除全部路由后回到空的路由表
+	for _, route := range []string{"/user", "/user/:id<int>/orders", "/post/*/comments", "/user/:name"} {
+		assert.True(t, r.removeRoute("", http.MethodGet, route))
+	}
+	assert.Empty(t, r.load().trees)
+}
+
+func TestRouter_addRoute_replace(t *testing.T) {
+	r := newRouter()
+	r.addRoute(http.MethodGet, "/user/:id", mockHandler)
+	old := r.load()
+
+	replaced := func(ctx *Context) {}
+	r.addHostRoute("", http.MethodGet, "/user/:id", routeEntry{
+		handlers:    []HandleFunc{mockHandler, replaced},
+		middlewares: 1,
+		replace:     true,
+	})
+	info, found := r.findRoute(http.MethodGet, "/user/1", nil)
+	assert.True(t, found)
+	assert.Len(t, info.node.handlers, 2)
+	assert.Equal(t, 1, info.node.middlewares)
+
+	info, _ = r.findRouteIn(old.trees, http.MethodGet, "/user/1", nil)
+	assert.Len(t, info.node.handlers, 1)
+
+	// 注册失败时路由表保持不变
+	cur := r.load()
+	assert.Panics(t, func() {
+		r.addRoute(http.MethodGet, "/user/:name/orders", mockHandler)
+	})
+	assert.Same(t, cur, r.load())
+}
+
+func TestRouter_findRoute_catchAll(t *testing.T) {
+	r := newRouter()
+	routes := []string{
+		"/static/*filepath",
+		"/static/css/main.css",
+		"/files/:id",
+		"/files/*path",
+		"/post/*/detail",
+		"/*any",
+	}
+	for _, route := range routes {
+		r.addRoute(http.MethodGet, route, mockHandler)
+	}
+
+	testCases := []struct {
+		name       string
+		path       string
+		wantFound  bool
+		wantRoute  string
+		wantParams Params
+	}{
+		{
+			name:       "rest of path",
+			path:       "/static/js/lib/app.js",
+			wantFound:  true,
+			wantRoute:  "/static/*filepath",
+			wantParams: Params{{Key: "filepath", Value: "js/lib/app.js"}},
+		},
+		{
+			name:      "static first",
+			path:      "/static/css/main.css",
+			wantFound: true,
+			wantRoute: "/static/css/main.css",
+		},
+		{
+			name:       "empty rest",
+			path:       "/static/",
+			wantFound:  true,
+			wantRoute:  "/static/*filepath",
+			wantParams: Params{{Key: "filepath", Value: ""}},
+		},
+		{
+			name:       "param before catch-all",
+			path:       "/files/123",
+			wantFound:  true,
+			wantRoute:  "/files/:id",
+			wantParams: Params{{Key: "id", Value: "123"}},
+		},
+		{
+			name:       "catch-all after param fails",
+			path:       "/files/123/raw",
+			wantFound:  true,
+			wantRoute:  "/files/*path",
+			wantParams: Params{{Key: "path", Value: "123/raw"}},
+		},
+		{
+			name:      "mid-path wildcard matches one segment",
+			path:      "/post/123/detail",
+			wantFound: true,
+			wantRoute: "/post/*/detail",
+		},
+		{
+			name:       "fall back to root catch-all",
+			path:       "/post/123/456/detail",
+			wantFound:  true,
+			wantRoute:  "/*any",
+			wantParams: Params{{Key: "any", Value: "post/123/456/detail"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			info, found := r.findRoute(http.MethodGet, tc.path, nil)
+			assert.Equal(t, tc.wantFound, found)
+			if !found {
+				return
+			}
+			assert.Equal(t, tc.wantRoute, info.node.route)
+			assert.Equal(t, tc.wantParams, info.pathParams)
+		})
+	}
+}
+
+func TestRouter_findRoute_constraint(t *testing.T) {
+	r := newRouter()
+	routes := []string{
+		"/users/:id<int>",
+		"/users/:name",
+		"/users/:uid<uuid>/profile",
+		"/users/:id<int>/orders",
+		"/orders/:no(^[A-Z]{2}\\d+$)",
+		"/orders/:no(\\d+)/detail",
+		"/orders/:id/detail",
+	}
+	for _, route := range routes {
+		r.addRoute(http.MethodGet, route, mockHandler)
+	}
+
+	testCases := []struct {
+		name       string
+		path       string
+		wantFound  bool
+		wantRoute  string
+		wantParams Params
+	}{
+		{
+			name:       "int",
+			path:       "/users/42",
+			wantFound:  true,
+			wantRoute:  "/users/:id<int>",
+			wantParams: Params{{Key: "id", Value: "42"}},
+		},
+		{
+			name:       "unconstrained",
+			path:       "/users/tom",
+			wantFound:  true,
+			wantRoute:  "/users/:name",
+			wantParams: Params{{Key: "name", Value: "tom"}},
+		},
+		{
+			name:       "uuid",
+			path:       "/users/0b7e7e4c-0c6f-4d8a-9c3e-7b5f5f0a1c2d/profile",
+			wantFound:  true,
+			wantRoute:  "/users/:uid<uuid>/profile",
+			wantParams: Params{{Key: "uid", Value: "0b7e7e4c-0c6f-4d8a-9c3e-7b5f5f0a1c2d"}},
+		},
+		{
+			name:      "uuid mismatch",
+			path:      "/users/42/profile",
+			wantFound: false,
+		},
+		{
+			name:       "same constraint deeper",
+			path:       "/users/42/orders",
+			wantFound:  true,
+			wantRoute:  "/users/:id<int>/orders",
+			wantParams: Params{{Key: "id", Value: "42"}},
+		},
+		{
+			name:       "regexp",
+			path:       "/orders/CN123",
+			wantFound:  true,
+			wantRoute:  "/orders/:no(^[A-Z]{2}\\d+$)",
+			wantParams: Params{{Key: "no", Value: "CN123"}},
+		},
+		{
+			name:      "regexp mismatch",
+			path:      "/orders/cn123",
+			wantFound: false,
+		},
+		{
+			name:       "regexp is anchored",
+			path:       "/orders/a1/detail",
+			wantFound:  true,
+			wantRoute:  "/orders/:id/detail",
+			wantParams: Params{{Key: "id", Value: "a1"}},
+		},
+		{
+			name:       "constrained before unconstrained",
+			path:       "/orders/1/detail",
+			wantFound:  true,
+			wantRoute:  "/orders/:no(\\d+)/detail",
+			wantParams: Params{{Key: "no", Value: "1"}},
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			n, found := r.findRoute(tc.method, tc.path)
+			info, found := r.findRoute(http.MethodGet, tc.path, nil)
 			assert.Equal(t, tc.wantFound, found)
 			if !found {
 				return
 			}
-			assert.Equal(t, tc.info.node.path, n.node.path)
-			assert.Equal(t, tc.info.pathParams, n.pathParams)
+			assert.Equal(t, tc.wantRoute, info.node.route)
+			assert.Equal(t, tc.wantParams, info.pathParams)
+		})
+	}
+}
+
+func newBenchmarkRouter() *router {
+	r := newRouter()
+	routes := []string{
+		"/",
+		"/user",
+		"/user/home",
+		"/user/profile/settings",
+		"/user/:id",
+		"/user/:id/orders/:orderId",
+		"/order/:no<int>",
+		"/order/:no<int>/detail",
+		"/static/*filepath",
+		"/post/*/comments",
+	}
+	for _, route := range routes {
+		r.addRoute(http.MethodGet, route, mockHandler)
+	}
+	return r
+}
+
+var benchmarkPaths = []struct {
+	name string
+	path string
+}{
+	{name: "static", path: "/user/profile/settings"},
+	{name: "param", path: "/user/123/orders/456"},
+	{name: "constrained param", path: "/order/123/detail"},
+	{name: "wildcard", path: "/post/123/comments"},
+	{name: "catch-all", path: "/static/js/lib/app.js"},
+}
+
+func BenchmarkRouter_findRoute(b *testing.B) {
+	r := newBenchmarkRouter()
+	for _, bp := range benchmarkPaths {
+		b.Run(bp.name, func(b *testing.B) {
+			params := make(Params, 0, r.load().maxParams)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, _ = r.findRoute(http.MethodGet, bp.path, params[:0])
+			}
+		})
+	}
+}
+
+func TestRouter_findRoute_zeroAlloc(t *testing.T) {
+	r := newBenchmarkRouter()
+	params := make(Params, 0, r.load().maxParams)
+	for _, bp := range benchmarkPaths {
+		t.Run(bp.name, func(t *testing.T) {
+			allocs := testing.AllocsPerRun(100, func() {
+				_, ok := r.findRoute(http.MethodGet, bp.path, params[:0])
+				if !ok {
+					t.Fatalf("route not found: %s", bp.path)
+				}
+			})
+			assert.Zero(t, allocs)
+		})
+	}
+}
+
+func TestRouter_findHostRoute_zeroAlloc(t *testing.T) {
+	r := newRouter()
+	r.addHostRoute("api.example.com", http.MethodGet, "/users/:id", routeEntry{handlers: []HandleFunc{mockHandler}})
+	r.addHostRoute(":tenant.example.com", http.MethodGet, "/users/:id", routeEntry{handlers: []HandleFunc{mockHandler}})
+	params := make(Params, 0, r.load().maxParams)
+	assert.Equal(t, 2, r.load().maxParams)
+
+	for _, host := range []string{"api.example.com", "acme.example.com:8080"} {
+		t.Run(host, func(t *testing.T) {
+			allocs := testing.AllocsPerRun(100, func() {
+				_, ok := r.findHostRoute(host, http.MethodGet, "/users/123", params[:0])
+				if !ok {
+					t.Fatalf("route not found: %s", host)
+				}
+			})
+			assert.Zero(t, allocs)
 		})
 	}
 }
@@ -1,8 +1,10 @@
 package web
 
 import (
+	"io/fs"
 	"net/http"
 	"path"
+	"strings"
 )
 
 type IRouterGroup interface {
@@ -15,26 +17,79 @@ type IRouterGroup interface {
 	PUT(path string, handlers ...HandleFunc) IRouterGroup
 	PATCH(path string, handlers ...HandleFunc) IRouterGroup
 	OPTIONS(path string, handlers ...HandleFunc) IRouterGroup
+	HEAD(path string, handlers ...HandleFunc) IRouterGroup
+	// DisableAutoOptions 此后在该分组(及其子分组)注册的路由不参与 OPTIONS 自动应答
+	DisableAutoOptions() IRouterGroup
+	// Name 给注册路由时返回的路由命名，用于 Engine.URL 反向生成url，分组本身不能命名
+	//
+	//	e.GET("/users/:id", handler).Name("user.show")
+	Name(name string) IRouterGroup
+	// WithMeta 返回带有元数据的分组，在其中注册的路由和元数据同时生效，处理请求时通过 Context.Meta 读取
+	// key 的使用方式和 context.WithValue 相同，应该使用自定义类型避免冲突
+	WithMeta(key any, val any) IRouterGroup
+	// Meta 给注册路由时返回的路由设置元数据，Mount 时为注册的全部路由
+	// 元数据在路由生效之后才设置，服务运行时注册的路由应该使用 WithMeta
+	Meta(key any, val any) IRouterGroup
+	// Replace 和 Handle 相同，但路由已存在时替换它的处理链
+	Replace(httpMethod, path string, handlers ...HandleFunc) IRouterGroup
+	// Remove 删除该分组下的路由，路由不存在时返回 false
+	Remove(httpMethod, path string) bool
+	// Mount 把 prefix 下所有http method的请求去掉前缀后交给 h 处理，h 可以是另一个 Engine
+	Mount(prefix string, h http.Handler) IRouterGroup
+	Static(prefix string, dir string, opts ...StaticOption) IRouterGroup
+	StaticFS(prefix string, fsys fs.FS, opts ...StaticOption) IRouterGroup
 }
 
 var _ IRouterGroup = &RouterGroup{}
+var _ IRouterGroup = groupRoute{}
 
+// RouterGroup 注册、替换和删除路由都可以在服务运行时进行，可以并发注册
 type RouterGroup struct {
 	engine   *Engine
 	handlers []HandleFunc
 	basePath string
+	// 不为空时分组内的路由只匹配该 host
+	host string
+
+	noAutoOptions bool
+	// 在该分组注册的路由的元数据
+	meta map[any]any
+}
+
+// groupRoute 注册路由时返回，Name 和 Meta 作用于刚注册的路由，其余方法作用于所在的分组
+type groupRoute struct {
+	*RouterGroup
+	// Mount 会同时注册多个
+	keys []routeKey
+}
+
+func (r groupRoute) Name(name string) IRouterGroup {
+	r.engine.nameRoute(name, r.keys[0])
+	return r
+}
+
+func (r groupRoute) Meta(key any, val any) IRouterGroup {
+	r.engine.setMeta(r.keys, key, val)
+	return r
 }
 
 func (g *RouterGroup) Group(relativePath string) IRouterGroup {
 	return &RouterGroup{
-		engine:   g.engine,
-		handlers: g.handlers,
-		basePath: g.resolvePath(relativePath),
+		engine:        g.engine,
+		handlers:      g.handlers,
+		basePath:      g.resolvePath(relativePath),
+		host:          g.host,
+		noAutoOptions: g.noAutoOptions,
+		meta:          g.meta,
 	}
 }
 
 func (g *RouterGroup) resolvePath(relativePath string) string {
 	absolutePath := path.Join(g.basePath, relativePath)
+	// path.Join 会去掉末尾的 /，严格模式下需要保留
+	if strings.HasSuffix(relativePath, "/") && !strings.HasSuffix(absolutePath, "/") {
+		absolutePath += "/"
+	}
 	return absolutePath
 }
 
@@ -44,12 +99,74 @@ func (g *RouterGroup) Use(middlewares ...HandleFunc) IRouterGroup {
 }
 
 func (g *RouterGroup) Handle(httpMethod, path string, handlers ...HandleFunc) IRouterGroup {
-	if len(handlers) == 0 || handlers[0] == nil {
+	return g.handle(httpMethod, path, false, handlers)
+}
+
+func (g *RouterGroup) Replace(httpMethod, path string, handlers ...HandleFunc) IRouterGroup {
+	return g.handle(httpMethod, path, true, handlers)
+}
+
+func (g *RouterGroup) handle(httpMethod, path string, replace bool, handlers []HandleFunc) groupRoute {
+	return g.addRoute(httpMethod, path, routeEntry{handlers: handlers, replace: replace})
+}
+
+// addRoute 在 entry 的处理链前加上分组中间件，并应用分组的设置
+func (g *RouterGroup) addRoute(httpMethod, path string, entry routeEntry) groupRoute {
+	if len(entry.handlers) == 0 || entry.handlers[0] == nil {
 		panic("HandleFunc is empty")
 	}
 	absolutePath := g.resolvePath(path)
-	combinedHandlers := append(g.handlers, handlers...)
-	g.engine.addRoute(httpMethod, absolutePath, combinedHandlers...)
+	// 复制一份，避免不同路由共用 g.handlers 的底层数组
+	combinedHandlers := make([]HandleFunc, 0, len(g.handlers)+len(entry.handlers))
+	combinedHandlers = append(combinedHandlers, g.handlers...)
+	combinedHandlers = append(combinedHandlers, entry.handlers...)
+	entry.handlers = combinedHandlers
+	entry.middlewares = len(g.handlers)
+	entry.noAutoOptions = g.noAutoOptions
+	entry.meta = g.meta
+	g.engine.addHostRoute(g.host, httpMethod, absolutePath, entry)
+	return groupRoute{RouterGroup: g, keys: []routeKey{{host: g.host, method: httpMethod, route: absolutePath}}}
+}
+
+func (g *RouterGroup) Remove(httpMethod, path string) bool {
+	return g.engine.removeRoute(g.host, httpMethod, g.resolvePath(path))
+}
+
+func (g *RouterGroup) Mount(prefix string, h http.Handler) IRouterGroup {
+	handler := mountHandler(h)
+	// *mountpath 同时匹配 prefix 本身
+	route := path.Join(prefix, "*"+mountParam)
+	keys := make([]routeKey, 0, len(anyMethods))
+	for _, method := range anyMethods {
+		keys = append(keys, g.handle(method, route, false, []HandleFunc{handler}).keys...)
+	}
+	return groupRoute{RouterGroup: g, keys: keys}
+}
+
+func (g *RouterGroup) Name(name string) IRouterGroup {
+	panic("no route to name")
+}
+
+// WithMeta 返回的分组和 g 有相同的路径、中间件和设置，g 本身不受影响
+//
+//	e.WithMeta(scopesKey{}, []string{"admin"}).GET("/admin", handler)
+func (g *RouterGroup) WithMeta(key any, val any) IRouterGroup {
+	return &RouterGroup{
+		engine:        g.engine,
+		handlers:      g.handlers,
+		basePath:      g.basePath,
+		host:          g.host,
+		noAutoOptions: g.noAutoOptions,
+		meta:          mergeMeta(g.meta, map[any]any{key: val}),
+	}
+}
+
+func (g *RouterGroup) Meta(key any, val any) IRouterGroup {
+	panic("no route to set meta")
+}
+
+func (g *RouterGroup) DisableAutoOptions() IRouterGroup {
+	g.noAutoOptions = true
 	return g
 }
 
@@ -76,3 +193,7 @@ func (g *RouterGroup) PATCH(path string, handlers ...HandleFunc) IRouterGroup {
 func (g *RouterGroup) OPTIONS(path string, handlers ...HandleFunc) IRouterGroup {
 	return g.Handle(http.MethodOptions, path, handlers...)
 }
+
+func (g *RouterGroup) HEAD(path string, handlers ...HandleFunc) IRouterGroup {
+	return g.Handle(http.MethodHead, path, handlers...)
+}
@@ -2,9 +2,13 @@ package web
 
 import (
 	"fmt"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
+	"testing/fstest"
 )
 
 var _ http.ResponseWriter = &MockWriter{}
@@ -60,3 +64,193 @@ func TestRouterGroup_Group(t *testing.T) {
 	require.NoError(t, err)
 	base.engine.ServeHTTP(mockWriter, mockRequest)
 }
+
+func TestRouterGroup_Mount(t *testing.T) {
+	child := NewEngine()
+	child.GET("/users/:id", func(ctx *Context) {
+		_ = ctx.String(http.StatusOK, "user "+ctx.Param("id"))
+	})
+
+	e := NewEngine()
+	api := e.Group("/api")
+	api.Use(func(ctx *Context) {
+		ctx.Resp.Header().Set("X-Middleware", "api")
+		ctx.Next()
+	})
+	api.Mount("/v1", child)
+	api.Mount("/raw", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(r.Method + " " + r.URL.Path + "?" + r.URL.RawQuery))
+	}))
+	e.GET("/wrapped/:id", WrapF(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(r.URL.Path))
+	}))
+	e.GET("/empty", WrapH(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	e.Mount("/files", http.FileServer(http.FS(fstest.MapFS{
+		"sub/index.html": {Data: []byte("index")},
+		"sub/a.txt":      {Data: []byte("a")},
+	})))
+	strictChild := NewEngine(WithStrictSlash(true))
+	strictChild.GET("/dir/", func(ctx *Context) {
+		_ = ctx.String(http.StatusOK, "dir "+ctx.Req.URL.Path)
+	})
+	e.Mount("/app", strictChild)
+
+	testCases := []struct {
+		name           string
+		method         string
+		path           string
+		wantStatus     int
+		wantBody       string
+		wantMiddleware string
+	}{
+		{
+			name:           "sub engine",
+			method:         http.MethodGet,
+			path:           "/api/v1/users/42",
+			wantStatus:     http.StatusOK,
+			wantBody:       "user 42",
+			wantMiddleware: "api",
+		},
+		{
+			name:           "sub engine not found",
+			method:         http.MethodGet,
+			path:           "/api/v1/orders",
+			wantStatus:     http.StatusNotFound,
+			wantBody:       "404 page not found",
+			wantMiddleware: "api",
+		},
+		{
+			name:           "prefix stripped",
+			method:         http.MethodPost,
+			path:           "/api/raw/a/b?x=1",
+			wantStatus:     http.StatusOK,
+			wantBody:       "POST /a/b?x=1",
+			wantMiddleware: "api",
+		},
+		{
+			name:           "prefix itself",
+			method:         http.MethodDelete,
+			path:           "/api/raw",
+			wantStatus:     http.StatusOK,
+			wantBody:       "DELETE /?",
+			wantMiddleware: "api",
+		},
+		{
+			name:           "head without body",
+			method:         http.MethodHead,
+			path:           "/api/raw/a",
+			wantStatus:     http.StatusOK,
+			wantMiddleware: "api",
+		},
+		{
+			name:       "file server directory",
+			method:     http.MethodGet,
+			path:       "/files/sub/",
+			wantStatus: http.StatusOK,
+			wantBody:   "index",
+		},
+		{
+			name:       "file server file",
+			method:     http.MethodGet,
+			path:       "/files/sub/a.txt",
+			wantStatus: http.StatusOK,
+			wantBody:   "a",
+		},
+		{
+			name:       "strict child keeps trailing slash",
+			method:     http.MethodGet,
+			path:       "/app/dir/",
+			wantStatus: http.StatusOK,
+			wantBody:   "dir /dir/",
+		},
+		{
+			name:       "strict child without trailing slash",
+			method:     http.MethodGet,
+			path:       "/app/dir",
+			wantStatus: http.StatusNotFound,
+			wantBody:   "404 page not found",
+		},
+		{
+			name:       "wrapped handler keeps path",
+			method:     http.MethodGet,
+			path:       "/wrapped/1",
+			wantStatus: http.StatusCreated,
+			wantBody:   "/wrapped/1",
+		},
+		{
+			name:       "wrapped handler writes nothing",
+			method:     http.MethodGet,
+			path:       "/empty",
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			e.ServeHTTP(recorder, httptest.NewRequest(tc.method, tc.path, nil))
+			assert.Equal(t, tc.wantStatus, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+			assert.Equal(t, tc.wantMiddleware, recorder.Header().Get("X-Middleware"))
+		})
+	}
+}
+
+func TestRouterGroup_WithMeta_runtime(t *testing.T) {
+	e := NewEngine()
+	e.GET("/", userHandler)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			e.WithMeta(tierKey{}, "gold").Mount(fmt.Sprintf("/m%d", i), http.NotFoundHandler())
+		}
+	}()
+
+	// 运行时注册的路由匹配到时一定已经有元数据
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+		for i := 0; i < 100; i++ {
+			info, ok := e.match("", http.MethodPost, fmt.Sprintf("/m%d/x", i), nil)
+			if ok {
+				require.Equal(t, "gold", info.node.meta[tierKey{}])
+			}
+		}
+	}
+}
+
+func TestRouterGroup_concurrentRegister(t *testing.T) {
+	e := NewEngine()
+	users := e.Group("/users")
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				id := fmt.Sprintf("%d-%d", i, j)
+				users.GET("/"+id, userHandler).Name(id).Meta(tierKey{}, id)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// 命名和元数据不会设置到其他 goroutine 注册的路由上
+	for i := 0; i < 8; i++ {
+		for j := 0; j < 50; j++ {
+			id := fmt.Sprintf("%d-%d", i, j)
+			url, err := e.URL(id)
+			require.NoError(t, err)
+			assert.Equal(t, "/users/"+id, url)
+			info, ok := e.match("", http.MethodGet, "/users/"+id, nil)
+			require.True(t, ok)
+			assert.Equal(t, id, info.node.meta[tierKey{}])
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package web
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"runtime"
+	"sort"
+	"text/tabwriter"
+)
+
+// RouteInfo 一条已注册路由的信息
+type RouteInfo struct {
+	// Host 为空时匹配任意 host
+	Host   string `json:"host,omitempty"`
+	Method string `json:"method"`
+	// Path 注册时的完整路由，包含分组前缀
+	Path string `json:"path"`
+	Name string `json:"name,omitempty"`
+	// Handlers 处理链中每个函数的名字，包括中间件
+	Handlers []string `json:"handlers"`
+	// Middlewares 处理链中来自分组 Use 的中间件数量
+	Middlewares int `json:"middlewares"`
+}
+
+// Routes 返回所有已注册的路由，按 host、路由和 http method 排序
+func (e *Engine) Routes() []RouteInfo {
+	t := e.load()
+	names := make(map[routeKey]string, len(t.names))
+	for name, key := range t.names {
+		names[key] = name
+	}
+
+	var res []RouteInfo
+	collect := func(host string, trees methodTrees) {
+		for method, root := range trees {
+			root.walk(func(n *node) {
+				if n.handlers == nil {
+					return
+				}
+				handlers := make([]string, 0, len(n.handlers))
+				for _, h := range n.handlers {
+					handlers = append(handlers, nameOfFunction(h))
+				}
+				res = append(res, RouteInfo{
+					Host:        host,
+					Method:      method,
+					Path:        n.route,
+					Name:        names[routeKey{host: host, method: method, route: n.route}],
+					Handlers:    handlers,
+					Middlewares: n.middlewares,
+				})
+			})
+		}
+	}
+	collect("", t.trees)
+	for host, trees := range t.exactHosts {
+		collect(host, trees)
+	}
+	for _, h := range t.paramHosts {
+		collect(h.pattern, h.trees)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Host != res[j].Host {
+			return res[i].Host < res[j].Host
+		}
+		if res[i].Path != res[j].Path {
+			return res[i].Path < res[j].Path
+		}
+		return res[i].Method < res[j].Method
+	})
+	return res
+}
+
+// PrintRoutes 以表格形式输出路由表，最后一列为路由的最终处理函数
+func (e *Engine) PrintRoutes(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "HOST\tMETHOD\tPATH\tNAME\tMIDDLEWARES\tHANDLER")
+	for _, r := range e.Routes() {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\n",
+			r.Host, r.Method, r.Path, r.Name, r.Middlewares, r.Handlers[len(r.Handlers)-1])
+	}
+	_ = tw.Flush()
+}
+
+// RoutesHandler 返回以 JSON 输出路由表的 HandleFunc，用于注册调试接口
+//
+//	e.GET("/debug/routes", e.RoutesHandler())
+func (e *Engine) RoutesHandler() HandleFunc {
+	return func(ctx *Context) {
+		_ = ctx.JSON(http.StatusOK, e.Routes())
+	}
+}
+
+// walk 深度优先遍历子树
+func (n *node) walk(fn func(n *node)) {
+	fn(n)
+	for _, child := range n.children {
+		child.walk(fn)
+	}
+	for _, child := range n.constrainedChildren {
+		child.walk(fn)
+	}
+	for _, child := range []*node{n.paramChild, n.starChild, n.catchAllChild} {
+		if child != nil {
+			child.walk(fn)
+		}
+	}
+}
+
+func nameOfFunction(f any) string {
+	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+}
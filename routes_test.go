@@ -0,0 +1,74 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func authMiddleware(ctx *Context) {
+	ctx.Next()
+}
+
+func userHandler(ctx *Context) {
+	ctx.Status(http.StatusOK)
+}
+
+func TestEngine_Routes(t *testing.T) {
+	e := NewEngine()
+	e.GET("/", userHandler)
+	api := e.Group("/api")
+	api.Use(authMiddleware)
+	api.GET("/users/:id", userHandler).Name("user.show")
+	api.POST("/users/:id", userHandler)
+	admin := api.Group("/admin")
+	admin.Use(authMiddleware)
+	admin.DELETE("/users/:id", userHandler)
+
+	want := []RouteInfo{
+		{
+			Method:   http.MethodGet,
+			Path:     "/",
+			Handlers: []string{"github.com/KNICEX/go-web.userHandler"},
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/api/admin/users/:id",
+			Handlers:    []string{"github.com/KNICEX/go-web.authMiddleware", "github.com/KNICEX/go-web.authMiddleware", "github.com/KNICEX/go-web.userHandler"},
+			Middlewares: 2,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/users/:id",
+			Name:        "user.show",
+			Handlers:    []string{"github.com/KNICEX/go-web.authMiddleware", "github.com/KNICEX/go-web.userHandler"},
+			Middlewares: 1,
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/users/:id",
+			Handlers:    []string{"github.com/KNICEX/go-web.authMiddleware", "github.com/KNICEX/go-web.userHandler"},
+			Middlewares: 1,
+		},
+	}
+	assert.Equal(t, want, e.Routes())
+
+	buf := &bytes.Buffer{}
+	e.PrintRoutes(buf)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 5)
+	assert.Equal(t, []string{"HOST", "METHOD", "PATH", "NAME", "MIDDLEWARES", "HANDLER"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"GET", "/api/users/:id", "user.show", "1", "github.com/KNICEX/go-web.userHandler"}, strings.Fields(lines[3]))
+
+	e.GET("/debug/routes", e.RoutesHandler())
+	recorder := httptest.NewRecorder()
+	e.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/routes", nil))
+	var got []RouteInfo
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+	assert.Len(t, got, 5)
+}
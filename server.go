@@ -1,9 +1,13 @@
 package web
 
 import (
+	"io"
 	"log"
 	"net"
 	"net/http"
+	"sort"
+	"strings"
+	"sync"
 )
 
 type HandleFunc func(ctx *Context)
@@ -22,7 +26,32 @@ type Engine struct {
 	*router
 	RouterGroup
 	NotFoundHandler HandleFunc
-	AfterStart      func(l net.Listener)
+	// MethodNotAllowedHandler 路径存在但请求方法未注册时调用，Allow 头已经在调用前设置好
+	MethodNotAllowedHandler HandleFunc
+	// HandleOptions 为 true 时，没有显式注册 OPTIONS 的路径会自动应答 OPTIONS 请求
+	HandleOptions bool
+	// OptionsHandler 自动应答 OPTIONS 请求时调用，Allow 头已经在调用前设置好
+	OptionsHandler HandleFunc
+	// RedirectTrailingSlash 严格模式下找不到路由时，如果增加或去掉末尾的 / 后能匹配则重定向
+	RedirectTrailingSlash bool
+	// RedirectFixedPath 找不到路由时，清理路径中的 .、..、重复的 / 并忽略大小写重新查找，找到则重定向
+	RedirectFixedPath bool
+	// BindErrorHandler Context.Bind、Context.BindJSON 绑定或校验失败时调用，为 nil 时只返回错误，通过 Context.Error 记录后由 ErrorHandler 输出 400
+	BindErrorHandler func(ctx *Context, err error)
+	// MaxBodySize Context.BindBody、Context.BindJSON 读取的请求体字节数上限，超过时返回 413，小于等于 0 时不限制
+	MaxBodySize int64
+	// StrictBody 为 true 时 Context.BindBody、Context.BindJSON 遇到结构体中不存在的字段返回 400
+	StrictBody bool
+	// ErrorHandler 处理链执行完成后处理 Context.Error 记录的错误，为 nil 时不处理
+	ErrorHandler func(ctx *Context, err error)
+	// Templates Context.Render 使用的模板，通过 WithTemplates 或 WithTemplateDir 设置
+	Templates *Templates
+	// RoutesOutput 不为 nil 时，Start 会把路由表输出到这里
+	RoutesOutput io.Writer
+	AfterStart   func(l net.Listener)
+
+	// 复用 Context，请求处理完成后放回
+	pool sync.Pool
 }
 
 var DefaultNotFoundHandler = func(ctx *Context) {
@@ -30,15 +59,32 @@ var DefaultNotFoundHandler = func(ctx *Context) {
 	ctx.RespData = []byte("404 page not found")
 }
 
+var DefaultMethodNotAllowedHandler = func(ctx *Context) {
+	ctx.StatusCode = http.StatusMethodNotAllowed
+	ctx.RespData = []byte("405 method not allowed")
+}
+
+var DefaultOptionsHandler = func(ctx *Context) {
+	ctx.StatusCode = http.StatusNoContent
+}
+
 func NewEngine(opts ...EngineOption) *Engine {
 	res := &Engine{
 		router: newRouter(),
 		RouterGroup: RouterGroup{
 			basePath: "/",
 		},
-		NotFoundHandler: DefaultNotFoundHandler,
+		NotFoundHandler:         DefaultNotFoundHandler,
+		MethodNotAllowedHandler: DefaultMethodNotAllowedHandler,
+		HandleOptions:           true,
+		OptionsHandler:          DefaultOptionsHandler,
+		BindErrorHandler:        DefaultBindErrorHandler,
+		ErrorHandler:            DefaultErrorHandler,
 	}
 	res.RouterGroup.engine = res
+	res.pool.New = func() any {
+		return &Context{}
+	}
 	for _, opt := range opts {
 		opt(res)
 	}
@@ -51,34 +97,239 @@ func WithNotFoundHandler(h HandleFunc) EngineOption {
 	}
 }
 
+func WithMethodNotAllowedHandler(h HandleFunc) EngineOption {
+	return func(e *Engine) {
+		e.MethodNotAllowedHandler = h
+	}
+}
+
+func WithHandleOptions(handle bool) EngineOption {
+	return func(e *Engine) {
+		e.HandleOptions = handle
+	}
+}
+
+func WithOptionsHandler(h HandleFunc) EngineOption {
+	return func(e *Engine) {
+		e.OptionsHandler = h
+	}
+}
+
+func WithBindErrorHandler(h func(ctx *Context, err error)) EngineOption {
+	return func(e *Engine) {
+		e.BindErrorHandler = h
+	}
+}
+
+func WithErrorHandler(h func(ctx *Context, err error)) EngineOption {
+	return func(e *Engine) {
+		e.ErrorHandler = h
+	}
+}
+
+func WithMaxBodySize(size int64) EngineOption {
+	return func(e *Engine) {
+		e.MaxBodySize = size
+	}
+}
+
+func WithStrictBody(strict bool) EngineOption {
+	return func(e *Engine) {
+		e.StrictBody = strict
+	}
+}
+
+// WithStrictSlash 开启严格模式，路由和请求路径按原样匹配，/users/ 和 /users 是不同的路由
+// 需要在注册路由之前生效，所以只能通过 EngineOption 设置
+func WithStrictSlash(strict bool) EngineOption {
+	return func(e *Engine) {
+		e.strictSlash = strict
+	}
+}
+
+func WithRedirectTrailingSlash(redirect bool) EngineOption {
+	return func(e *Engine) {
+		e.RedirectTrailingSlash = redirect
+	}
+}
+
+func WithRedirectFixedPath(redirect bool) EngineOption {
+	return func(e *Engine) {
+		e.RedirectFixedPath = redirect
+	}
+}
+
+// WithPrintRoutes 启动时把路由表输出到 w，用于检查中间件是否挂载到了每个路由上
+func WithPrintRoutes(w io.Writer) EngineOption {
+	return func(e *Engine) {
+		e.RoutesOutput = w
+	}
+}
+
 func WithAfterStart(h func(l net.Listener)) EngineOption {
 	return func(e *Engine) {
 		e.AfterStart = h
 	}
 }
 
+// ServeHTTP 使用的 Context 来自 sync.Pool，请求处理完成后会被重置并用于其他请求
+// 因此处理函数返回后不能再使用 Context 及其 PathParams、Values，需要在其他 goroutine 中使用时先调用 Context.Copy
 func (e *Engine) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
-	ctx := newContext(writer, request)
+	ctx := e.pool.Get().(*Context)
+	ctx.reset(e, writer, request)
 	e.serve(ctx)
+	e.pool.Put(ctx)
 }
 
 func (e *Engine) serve(ctx *Context) {
-	info, ok := e.findRoute(ctx.Req.Method, ctx.Req.URL.Path)
-	if !ok || info.node.handlers == nil {
-		e.NotFoundHandler(ctx)
+	info, ok := e.match(ctx.Req.Host, ctx.Req.Method, ctx.Req.URL.Path, ctx.PathParams[:0])
+	if !ok {
+		e.handleUnmatched(ctx)
 	} else {
 		ctx.MatchedRoute = info.node.route
 		ctx.PathParams = info.pathParams
+		ctx.routeMeta = info.node.meta
 		ctx.handlers = info.node.handlers
 		ctx.Next()
 	}
 
+	e.handleErrors(ctx)
 	e.flushResp(ctx)
 }
 
+// match 查找能处理请求的路由，HEAD 请求在没有显式注册时使用 GET 的处理链
+func (e *Engine) match(host string, method string, path string, params Params) (matchInfo, bool) {
+	info, ok := e.findHostRoute(host, method, path, params)
+	if !ok && method == http.MethodHead {
+		info, ok = e.findHostRoute(host, http.MethodGet, path, params)
+	}
+	return info, ok
+}
+
+// handleUnmatched 当前方法没有匹配的路由时，如果其他方法注册了该路径则返回405，否则返回404
+// OPTIONS 请求在开启 HandleOptions 时自动应答
+func (e *Engine) handleUnmatched(ctx *Context) {
+	if e.redirect(ctx) {
+		return
+	}
+	allowed, autoOptions := e.allowedMethods(ctx.Req.Host, ctx.Req.URL.Path)
+	if len(allowed) == 0 {
+		e.NotFoundHandler(ctx)
+		return
+	}
+	autoOptions = autoOptions && e.HandleOptions
+	ctx.Resp.Header().Set("Allow", strings.Join(allowHeader(allowed, autoOptions), ", "))
+	if autoOptions && ctx.Req.Method == http.MethodOptions {
+		e.OptionsHandler(ctx)
+		return
+	}
+	e.MethodNotAllowedHandler(ctx)
+}
+
+// redirect 找不到路由时尝试修正路径，修正后能匹配则重定向到修正后的路径
+// GET、HEAD 请求使用 301，其他请求使用 308 保留请求方法和请求体
+func (e *Engine) redirect(ctx *Context) bool {
+	if !e.RedirectTrailingSlash && !e.RedirectFixedPath {
+		return false
+	}
+	req := ctx.Req
+	if req.Method == http.MethodConnect || req.URL.Path == "/" {
+		return false
+	}
+
+	fixed, ok := e.fixPath(req.Host, req.Method, req.URL.Path)
+	if !ok && req.Method == http.MethodHead {
+		fixed, ok = e.fixPath(req.Host, http.MethodGet, req.URL.Path)
+	}
+	if !ok {
+		return false
+	}
+	// 以 // 或 /\ 开头的 Location 会被浏览器当作其他域名
+	fixed = "/" + strings.TrimLeft(fixed, "/\\")
+
+	if req.URL.RawQuery != "" {
+		fixed += "?" + req.URL.RawQuery
+	}
+	ctx.Resp.Header().Set("Location", fixed)
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		ctx.StatusCode = http.StatusMovedPermanently
+	} else {
+		ctx.StatusCode = http.StatusPermanentRedirect
+	}
+	return true
+}
+
+// fixPath 依次尝试 增减末尾的 /、清理路径、忽略大小写，返回第一个能匹配的路径
+func (e *Engine) fixPath(host string, method string, path string) (string, bool) {
+	candidates := []string{path}
+	if e.RedirectTrailingSlash && e.strictSlash {
+		candidates = append(candidates, toggleTrailingSlash(path))
+	}
+	for i, p := range candidates {
+		if i > 0 {
+			if _, ok := e.findHostRoute(host, method, p, nil); ok {
+				return p, true
+			}
+		}
+		if !e.RedirectFixedPath {
+			continue
+		}
+		cleaned := cleanPath(p)
+		if cleaned != path {
+			if _, ok := e.findHostRoute(host, method, cleaned, nil); ok {
+				return cleaned, true
+			}
+		}
+		if fixed, ok := e.findFoldPath(host, method, cleaned); ok {
+			return fixed, true
+		}
+	}
+	return "", false
+}
+
+func toggleTrailingSlash(path string) string {
+	if strings.HasSuffix(path, "/") {
+		return path[:len(path)-1]
+	}
+	return path + "/"
+}
+
+// allowHeader 在已注册的方法基础上补充自动应答的 HEAD 和 OPTIONS
+func allowHeader(methods []string, autoOptions bool) []string {
+	res := make([]string, 0, len(methods)+2)
+	var hasGet, hasHead, hasOptions bool
+	for _, m := range methods {
+		switch m {
+		case http.MethodGet:
+			hasGet = true
+		case http.MethodHead:
+			hasHead = true
+		case http.MethodOptions:
+			hasOptions = true
+		}
+		res = append(res, m)
+	}
+	if hasGet && !hasHead {
+		res = append(res, http.MethodHead)
+	}
+	if autoOptions && !hasOptions {
+		res = append(res, http.MethodOptions)
+	}
+	sort.Strings(res)
+	return res
+}
+
 func (e *Engine) flushResp(ctx *Context) {
+	// 已经通过 Context.Writer 写出的响应不再输出
+	if ctx.Committed() {
+		return
+	}
+	if ctx.StatusCode == 0 {
+		ctx.StatusCode = http.StatusOK
+	}
 	ctx.Resp.WriteHeader(ctx.StatusCode)
-	if ctx.RespData != nil {
+	// HEAD 请求只返回响应头
+	if ctx.RespData != nil && ctx.Req.Method != http.MethodHead {
 		_, err := ctx.Resp.Write(ctx.RespData)
 		if err != nil {
 			log.Println("write response error:", err)
@@ -91,6 +342,9 @@ func (e *Engine) Start(addr string) error {
 	if err != nil {
 		return err
 	}
+	if e.RoutesOutput != nil {
+		e.PrintRoutes(e.RoutesOutput)
+	}
 	// 这里可以执行after start的操作
 	if e.AfterStart != nil {
 		e.AfterStart(l)
@@ -104,3 +358,18 @@ func (e *Engine) Handle(method string, path string, handlers ...HandleFunc) {
 	}
 	e.router.addRoute(method, path, handlers...)
 }
+
+// Host 返回只匹配 host 的路由分组，host 中 :name 形式的部分为参数，匹配到的值可以通过 Context.PathParams 获取
+// 请求的 host 匹配到某个分组后，只会在该 host 注册的路由中查找，host 中的端口会被忽略
+//
+//	e.Host("api.example.com").GET("/users", handler)
+//	e.Host(":tenant.example.com").GET("/", handler)
+func (e *Engine) Host(host string) IRouterGroup {
+	return &RouterGroup{
+		engine:        e,
+		handlers:      e.RouterGroup.handlers,
+		basePath:      "/",
+		host:          strings.ToLower(stripRegisteredPort(host)),
+		noAutoOptions: e.RouterGroup.noAutoOptions,
+	}
+}
@@ -1,7 +1,12 @@
 package web
 
 import (
+	"github.com/stretchr/testify/assert"
 	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -9,3 +14,525 @@ func TestServer(t *testing.T) {
 	var h Server
 	http.ListenAndServe(":8080", h)
 }
+
+func TestEngine_MethodNotAllowed(t *testing.T) {
+	e := NewEngine()
+	e.GET("/user/:id", mockHandler)
+	e.PUT("/user/:id", mockHandler)
+	e.POST("/order", mockHandler)
+
+	testCases := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantAllow  string
+	}{
+		{
+			name:       "method not allowed",
+			method:     http.MethodDelete,
+			path:       "/user/123",
+			wantStatus: http.StatusMethodNotAllowed,
+			wantAllow:  "GET, HEAD, OPTIONS, PUT",
+		},
+		{
+			name:       "single method",
+			method:     http.MethodGet,
+			path:       "/order",
+			wantStatus: http.StatusMethodNotAllowed,
+			wantAllow:  "OPTIONS, POST",
+		},
+		{
+			name:       "not found",
+			method:     http.MethodGet,
+			path:       "/goods",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			recorder := httptest.NewRecorder()
+			e.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantStatus, recorder.Code)
+			assert.Equal(t, tc.wantAllow, recorder.Header().Get("Allow"))
+		})
+	}
+}
+
+func TestWithMethodNotAllowedHandler(t *testing.T) {
+	e := NewEngine(WithMethodNotAllowedHandler(func(ctx *Context) {
+		_ = ctx.JSON(http.StatusMethodNotAllowed, map[string]string{"msg": "method not allowed"})
+	}))
+	e.GET("/user", mockHandler)
+
+	recorder := httptest.NewRecorder()
+	e.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/user", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+	assert.Equal(t, "GET, HEAD, OPTIONS", recorder.Header().Get("Allow"))
+	assert.JSONEq(t, `{"msg":"method not allowed"}`, recorder.Body.String())
+}
+
+func TestEngine_AutoHeadAndOptions(t *testing.T) {
+	e := NewEngine()
+	e.GET("/user", func(ctx *Context) {
+		_ = ctx.String(http.StatusOK, "user")
+	})
+	e.POST("/user", mockHandler)
+	e.OPTIONS("/order", func(ctx *Context) {
+		ctx.Status(http.StatusOK)
+	})
+	e.GET("/order", mockHandler)
+	admin := e.Group("/admin").DisableAutoOptions()
+	admin.GET("/dashboard", mockHandler)
+
+	testCases := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantAllow  string
+		wantBody   string
+	}{
+		{
+			name:       "head from get",
+			method:     http.MethodHead,
+			path:       "/user",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "auto options",
+			method:     http.MethodOptions,
+			path:       "/user",
+			wantStatus: http.StatusNoContent,
+			wantAllow:  "GET, HEAD, OPTIONS, POST",
+		},
+		{
+			name:       "explicit options",
+			method:     http.MethodOptions,
+			path:       "/order",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "auto options disabled by group",
+			method:     http.MethodOptions,
+			path:       "/admin/dashboard",
+			wantStatus: http.StatusMethodNotAllowed,
+			wantAllow:  "GET, HEAD",
+			wantBody:   "405 method not allowed",
+		},
+		{
+			name:       "allow lists head and options",
+			method:     http.MethodDelete,
+			path:       "/user",
+			wantStatus: http.StatusMethodNotAllowed,
+			wantAllow:  "GET, HEAD, OPTIONS, POST",
+			wantBody:   "405 method not allowed",
+		},
+		{
+			name:       "options not found",
+			method:     http.MethodOptions,
+			path:       "/goods",
+			wantStatus: http.StatusNotFound,
+			wantBody:   "404 page not found",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			recorder := httptest.NewRecorder()
+			e.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantStatus, recorder.Code)
+			assert.Equal(t, tc.wantAllow, recorder.Header().Get("Allow"))
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+		})
+	}
+}
+
+func TestWithHandleOptions(t *testing.T) {
+	e := NewEngine(WithHandleOptions(false))
+	e.GET("/user", mockHandler)
+
+	recorder := httptest.NewRecorder()
+	e.ServeHTTP(recorder, httptest.NewRequest(http.MethodOptions, "/user", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+	assert.Equal(t, "GET, HEAD", recorder.Header().Get("Allow"))
+}
+
+func TestEngine_Redirect(t *testing.T) {
+	e := NewEngine(WithStrictSlash(true), WithRedirectTrailingSlash(true), WithRedirectFixedPath(true))
+	e.GET("/users", userHandler)
+	e.GET("/users/:id/Profile", userHandler)
+	e.GET("/docs/", userHandler)
+	e.POST("/orders", userHandler)
+	e.GET("/static/*filepath", userHandler)
+
+	testCases := []struct {
+		name         string
+		method       string
+		path         string
+		wantStatus   int
+		wantLocation string
+	}{
+		{
+			name:         "remove trailing slash",
+			method:       http.MethodGet,
+			path:         "/users/",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/users",
+		},
+		{
+			name:         "add trailing slash",
+			method:       http.MethodGet,
+			path:         "/docs",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/docs/",
+		},
+		{
+			name:         "keep method",
+			method:       http.MethodPost,
+			path:         "/orders/",
+			wantStatus:   http.StatusPermanentRedirect,
+			wantLocation: "/orders",
+		},
+		{
+			name:         "duplicate slashes",
+			method:       http.MethodGet,
+			path:         "//users",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/users",
+		},
+		{
+			name:         "dot dot",
+			method:       http.MethodGet,
+			path:         "/docs/../users",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/users",
+		},
+		{
+			name:         "case insensitive keeps param case",
+			method:       http.MethodGet,
+			path:         "/USERS/Tom/profile",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/users/Tom/Profile",
+		},
+		{
+			name:         "head follows get",
+			method:       http.MethodHead,
+			path:         "/Users",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/users",
+		},
+		{
+			name:         "keep query",
+			method:       http.MethodGet,
+			path:         "/users/?page=2",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/users?page=2",
+		},
+		{
+			name:       "catch-all keeps trailing slash",
+			method:     http.MethodGet,
+			path:       "/static/js/",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "canonical path",
+			method:     http.MethodGet,
+			path:       "/users",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "not found",
+			method:     http.MethodGet,
+			path:       "/goods/",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			recorder := httptest.NewRecorder()
+			e.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantStatus, recorder.Code)
+			assert.Equal(t, tc.wantLocation, recorder.Header().Get("Location"))
+		})
+	}
+}
+
+func TestEngine_StrictSlash(t *testing.T) {
+	e := NewEngine(WithStrictSlash(true))
+	e.GET("/users", userHandler)
+	e.Group("/docs").GET("/", userHandler)
+
+	testCases := []struct {
+		path       string
+		wantStatus int
+	}{
+		{path: "/users", wantStatus: http.StatusOK},
+		{path: "/users/", wantStatus: http.StatusNotFound},
+		{path: "//users", wantStatus: http.StatusNotFound},
+		{path: "/docs/", wantStatus: http.StatusOK},
+		{path: "/docs", wantStatus: http.StatusNotFound},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			e.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			assert.Equal(t, tc.wantStatus, recorder.Code)
+		})
+	}
+
+	// 路径参数不匹配空的路径段，不会重定向到其他域名
+	e = NewEngine(WithStrictSlash(true), WithRedirectTrailingSlash(true), WithRedirectFixedPath(true))
+	e.GET("/:a/:b", userHandler)
+	e.GET("/files/:name(^\\w*$)/", userHandler)
+	for _, path := range []string{"//evil.com", "//evil.com/", "/\\evil.com/", "/files//"} {
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.NotEqual(t, http.StatusOK, recorder.Code, path)
+		location := recorder.Header().Get("Location")
+		assert.False(t, strings.HasPrefix(location, "//") || strings.HasPrefix(location, "/\\"), location)
+	}
+
+	// 非严格模式下忽略空的路径段
+	e = NewEngine(WithRedirectFixedPath(true))
+	e.GET("/users", userHandler)
+	for _, path := range []string{"/users", "/users/", "//users"} {
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	}
+}
+
+func TestEngine_Host(t *testing.T) {
+	e := NewEngine()
+	writeParams := func(name string) HandleFunc {
+		return func(ctx *Context) {
+			_ = ctx.JSON(http.StatusOK, map[string]any{
+				"name":   name,
+				"params": ctx.PathParams,
+			})
+		}
+	}
+	e.GET("/", writeParams("main"))
+	e.Host("api.example.com").GET("/users", writeParams("api"))
+	tenant := e.Host(":tenant.example.com")
+	tenant.Group("/users").GET("/:id", writeParams("tenant"))
+	e.Host(":tenant<int>.example.com").GET("/users/:id", writeParams("tenant int"))
+
+	testCases := []struct {
+		name       string
+		host       string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "exact host with port",
+			host:       "api.example.com:8080",
+			path:       "/users",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"name":"api","params":[]}`,
+		},
+		{
+			name:       "host is case insensitive",
+			host:       "API.Example.com",
+			path:       "/users",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"name":"api","params":[]}`,
+		},
+		{
+			name:       "host param",
+			host:       "acme.example.com",
+			path:       "/users/1",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"name":"tenant","params":[{"Key":"tenant","Value":"acme"},{"Key":"id","Value":"1"}]}`,
+		},
+		{
+			name:       "param hosts match in registration order",
+			host:       "42.example.com",
+			path:       "/users/1",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"name":"tenant","params":[{"Key":"tenant","Value":"42"},{"Key":"id","Value":"1"}]}`,
+		},
+		{
+			name:       "unknown host uses default routes",
+			host:       "example.com",
+			path:       "/",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"name":"main","params":[]}`,
+		},
+		{
+			name:       "matched host doesn't fall back",
+			host:       "api.example.com",
+			path:       "/",
+			wantStatus: http.StatusNotFound,
+			wantBody:   "404 page not found",
+		},
+		{
+			name:       "more labels",
+			host:       "a.b.example.com",
+			path:       "/users/1",
+			wantStatus: http.StatusNotFound,
+			wantBody:   "404 page not found",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.Host = tc.host
+			recorder := httptest.NewRecorder()
+			e.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantStatus, recorder.Code)
+			if recorder.Header().Get("Content-Type") == "application/json" {
+				assert.JSONEq(t, tc.wantBody, recorder.Body.String())
+			} else {
+				assert.Equal(t, tc.wantBody, recorder.Body.String())
+			}
+		})
+	}
+
+	routes := e.Routes()
+	assert.Len(t, routes, 4)
+	assert.Equal(t, "", routes[0].Host)
+	assert.Equal(t, ":tenant.example.com", routes[1].Host)
+	assert.Equal(t, "/users/:id", routes[1].Path)
+}
+
+func TestEngine_Host_port(t *testing.T) {
+	e := NewEngine()
+	e.Host("api.example.com:8080").GET("/users", userHandler)
+	e.Host("[::1]:8080").GET("/users", userHandler)
+	e.Host(":tenant.example.com").GET("/users", userHandler)
+
+	// 注册和请求中的端口都会被忽略
+	for _, host := range []string{"api.example.com", "api.example.com:8080", "api.example.com:9090", "[::1]:9090", "acme.example.com:8080"} {
+		req := httptest.NewRequest(http.MethodGet, "/users", nil)
+		req.Host = host
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, req)
+		assert.Equal(t, http.StatusOK, recorder.Code, host)
+	}
+	routes := e.Routes()
+	assert.Len(t, routes, 3)
+	assert.Equal(t, ":tenant.example.com", routes[0].Host)
+	assert.Equal(t, "[::1]", routes[1].Host)
+	assert.Equal(t, "api.example.com", routes[2].Host)
+}
+
+func TestEngine_RuntimeRoutes(t *testing.T) {
+	e := NewEngine()
+	e.GET("/ping", userHandler)
+
+	serve := func(path string) int {
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder.Code
+	}
+
+	// 注册、替换、删除路由的同时处理请求
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				assert.Equal(t, http.StatusOK, serve("/ping"))
+				serve("/plugins/1")
+			}
+		}()
+	}
+	plugins := e.Group("/plugins")
+	for i := 0; i < 100; i++ {
+		plugins.GET("/:id", userHandler)
+		plugins.Replace(http.MethodGet, "/:id", func(ctx *Context) {
+			ctx.Status(http.StatusAccepted)
+		})
+		assert.True(t, plugins.Remove(http.MethodGet, "/:id"))
+	}
+	close(stop)
+	wg.Wait()
+
+	assert.Equal(t, http.StatusNotFound, serve("/plugins/1"))
+	plugins.Replace(http.MethodGet, "/:id", userHandler).Name("plugin")
+	assert.Equal(t, http.StatusOK, serve("/plugins/1"))
+	plugins.Replace(http.MethodGet, "/:id", func(ctx *Context) {
+		ctx.Status(http.StatusAccepted)
+	})
+	assert.Equal(t, http.StatusAccepted, serve("/plugins/1"))
+	u, err := e.URL("plugin", "id", "1")
+	assert.NoError(t, err)
+	assert.Equal(t, "/plugins/1", u)
+	assert.False(t, e.Remove(http.MethodPost, "/plugins/:id"))
+}
+
+func TestEngine_ContextPool(t *testing.T) {
+	e := NewEngine()
+	var wg sync.WaitGroup
+	copies := make(chan *Context, 100)
+	e.GET("/users/:id", func(ctx *Context) {
+		_, ok := ctx.Get("id")
+		// 从 pool 中取出的 Context 不会残留上一个请求的数据
+		assert.False(t, ok)
+		ctx.Set("id", ctx.Param("id"))
+		copies <- ctx.Copy()
+		_ = ctx.String(http.StatusOK, ctx.Param("id"))
+	})
+
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := strconv.Itoa(i)
+			recorder := httptest.NewRecorder()
+			e.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/users/"+id, nil))
+			assert.Equal(t, id, recorder.Body.String())
+		}(i)
+	}
+	wg.Wait()
+	close(copies)
+
+	// 副本在请求结束后仍然可以安全使用
+	for c := range copies {
+		val, _ := c.Get("id")
+		assert.Equal(t, c.Param("id"), val)
+		assert.Equal(t, "/users/"+c.Param("id"), c.Req.URL.Path)
+	}
+}
+
+func BenchmarkEngine_ServeHTTP(b *testing.B) {
+	e := NewEngine()
+	e.GET("/users/:id", func(ctx *Context) {
+		ctx.Set("user", ctx.Param("id"))
+		ctx.Status(http.StatusOK)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	w := &MockWriter{}
+
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			e.ServeHTTP(w, req)
+		}
+	})
+	// 每个请求创建新的 Context，作为对比
+	b.Run("unpooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			ctx := newContext(w, req)
+			ctx.PathParams = make(Params, 0, e.load().maxParams)
+			e.serve(ctx)
+		}
+	})
+}
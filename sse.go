@@ -0,0 +1,166 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SSEvent 一条 Server-Sent Events 消息
+type SSEvent struct {
+	// ID 客户端重连时通过 Last-Event-ID 请求头带回
+	ID string
+	// Event 为空时客户端按 message 事件处理
+	Event string
+	// Data 为 string、[]byte 时原样发送，其他类型编码为 JSON，多行数据会拆分为多个 data 字段
+	Data any
+	// Retry 大于 0 时通知客户端断线重连的间隔
+	Retry time.Duration
+}
+
+// SSEvent 发送一条事件并立即 flush，第一次调用时提交 text/event-stream 响应
+func (c *Context) SSEvent(event string, data any) error {
+	return c.SSEWrite(SSEvent{Event: event, Data: data})
+}
+
+// SSEWrite 发送一条完整的事件并立即 flush
+func (c *Context) SSEWrite(ev SSEvent) error {
+	data, err := encodeSSEvent(ev)
+	if err != nil {
+		return err
+	}
+	return c.writeSSE(data)
+}
+
+// SSEComment 发送注释，客户端会忽略，通常用于保持连接
+func (c *Context) SSEComment(text string) error {
+	var buf bytes.Buffer
+	for _, line := range splitLines(text) {
+		buf.WriteString(": ")
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+	return c.writeSSE(buf.Bytes())
+}
+
+// LastEventID 返回客户端重连时带回的最后一条事件的 ID
+func (c *Context) LastEventID() string {
+	return c.Req.Header.Get("Last-Event-ID")
+}
+
+// SSEStream 保持连接并发送 events 中的事件，heartbeat 大于 0 时按间隔发送注释防止连接被代理断开
+// events 被关闭、写入失败或客户端断开连接时返回，返回值表示客户端是否已经断开
+//
+//	events := make(chan web.SSEvent)
+//	go produce(ctx.Copy(), ctx.LastEventID(), events)
+//	ctx.SSEStream(events, 15*time.Second)
+func (c *Context) SSEStream(events <-chan SSEvent, heartbeat time.Duration) bool {
+	c.startSSE()
+	c.Writer().Flush()
+
+	var tick <-chan time.Time
+	if heartbeat > 0 {
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	done := c.Req.Context().Done()
+	for {
+		select {
+		case <-done:
+			return true
+		case ev, ok := <-events:
+			if !ok {
+				return false
+			}
+			if err := c.SSEWrite(ev); err != nil {
+				return true
+			}
+		case <-tick:
+			if err := c.SSEComment("heartbeat"); err != nil {
+				return true
+			}
+		}
+	}
+}
+
+// startSSE 设置事件流的响应头并提交响应
+func (c *Context) startSSE() {
+	if c.Committed() {
+		return
+	}
+	header := c.Resp.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// 关闭 nginx 的响应缓冲
+	header.Set("X-Accel-Buffering", "no")
+	c.Writer().WriteHeader(http.StatusOK)
+}
+
+func (c *Context) writeSSE(data []byte) error {
+	c.startSSE()
+	w := c.Writer()
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	w.Flush()
+	return nil
+}
+
+func encodeSSEvent(ev SSEvent) ([]byte, error) {
+	var data string
+	switch val := ev.Data.(type) {
+	case nil:
+	case string:
+		data = val
+	case []byte:
+		data = string(val)
+	default:
+		res, err := json.Marshal(val)
+		if err != nil {
+			return nil, err
+		}
+		data = string(res)
+	}
+
+	var buf bytes.Buffer
+	// id 和 event 不能包含换行，否则会破坏事件格式
+	if ev.ID != "" {
+		buf.WriteString("id: ")
+		buf.WriteString(stripNewlines(ev.ID))
+		buf.WriteByte('\n')
+	}
+	if ev.Event != "" {
+		buf.WriteString("event: ")
+		buf.WriteString(stripNewlines(ev.Event))
+		buf.WriteByte('\n')
+	}
+	if ev.Retry > 0 {
+		buf.WriteString("retry: ")
+		buf.WriteString(strconv.FormatInt(ev.Retry.Milliseconds(), 10))
+		buf.WriteByte('\n')
+	}
+	for _, line := range splitLines(data) {
+		buf.WriteString("data: ")
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// splitLines 按 \r\n、\r、\n 切分
+func splitLines(s string) []string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+	return strings.Split(s, "\n")
+}
+
+func stripNewlines(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
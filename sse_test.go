@@ -0,0 +1,91 @@
+package web
+
+import (
+	"bufio"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContext_SSEvent(t *testing.T) {
+	e := NewEngine()
+	e.GET("/events", func(ctx *Context) {
+		_ = ctx.SSEvent("progress", map[string]int{"percent": 50})
+		_ = ctx.SSEWrite(SSEvent{
+			ID:    "2",
+			Event: "log",
+			Data:  "line1\nline2",
+			Retry: 3 * time.Second,
+		})
+		_ = ctx.SSEComment("ping")
+		_ = ctx.SSEvent("", []byte("done"))
+	})
+
+	recorder := httptest.NewRecorder()
+	e.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/events", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.True(t, recorder.Flushed)
+	assert.Equal(t, "text/event-stream", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "no-cache", recorder.Header().Get("Cache-Control"))
+	assert.Equal(t, "event: progress\ndata: {\"percent\":50}\n\n"+
+		"id: 2\nevent: log\nretry: 3000\ndata: line1\ndata: line2\n\n"+
+		": ping\n\n"+
+		"data: done\n\n", recorder.Body.String())
+}
+
+func TestContext_SSEStream(t *testing.T) {
+	e := NewEngine()
+	e.GET("/events", func(ctx *Context) {
+		events := make(chan SSEvent)
+		go func(lastID string) {
+			defer close(events)
+			events <- SSEvent{ID: lastID + "-1", Data: "a"}
+			// 等待心跳
+			time.Sleep(30 * time.Millisecond)
+			events <- SSEvent{ID: lastID + "-2", Data: "b"}
+		}(ctx.LastEventID())
+		ctx.SSEStream(events, 10*time.Millisecond)
+	})
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/events", nil)
+	require.NoError(t, err)
+	req.Header.Set("Last-Event-ID", "7")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if scanner.Text() != "" {
+			lines = append(lines, scanner.Text())
+		}
+	}
+	require.NoError(t, scanner.Err())
+	assert.Equal(t, "id: 7-1", lines[0])
+	assert.Equal(t, "data: a", lines[1])
+	assert.Contains(t, lines, ": heartbeat")
+	assert.Equal(t, []string{"id: 7-2", "data: b"}, lines[len(lines)-2:])
+}
+
+func TestContext_SSEStream_clientGone(t *testing.T) {
+	reqCtx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(reqCtx)
+	recorder := httptest.NewRecorder()
+	ctx := newContext(recorder, req)
+
+	events := make(chan SSEvent)
+	go func() {
+		events <- SSEvent{Data: "a"}
+		cancel()
+	}()
+	assert.True(t, ctx.SSEStream(events, 0))
+	assert.True(t, strings.HasPrefix(recorder.Body.String(), "data: a\n\n"))
+}
@@ -0,0 +1,277 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// staticParam Static 注册的 *name 通配符的参数名
+const staticParam = "filepath"
+
+type staticHandler struct {
+	engine *Engine
+	fs     fs.FS
+	// 请求目录时返回的文件，为空时不返回
+	index string
+	// 目录下没有 index 时是否列出目录内容
+	listing bool
+	// 大于 0 时设置 Cache-Control: max-age
+	maxAge time.Duration
+	// 找不到文件时返回根目录的 index，用于前端路由
+	spa bool
+	// 不返回 index 的路径前缀，如 /api
+	spaExclude []string
+	// 没有修改时间的文件根据内容生成的 ETag，key 为文件名
+	etags sync.Map
+}
+
+type StaticOption func(s *staticHandler)
+
+// WithStaticIndex 设置请求目录时返回的文件，默认为 index.html，为空时不返回
+func WithStaticIndex(name string) StaticOption {
+	return func(s *staticHandler) {
+		s.index = name
+	}
+}
+
+// WithStaticListing 目录下没有 index 文件时列出目录内容，默认关闭
+func WithStaticListing(enabled bool) StaticOption {
+	return func(s *staticHandler) {
+		s.listing = enabled
+	}
+}
+
+// WithStaticMaxAge 设置响应的 Cache-Control: public, max-age
+func WithStaticMaxAge(d time.Duration) StaticOption {
+	return func(s *staticHandler) {
+		s.maxAge = d
+	}
+}
+
+// WithSPAFallback 找不到文件时，对接受 text/html 的 GET、HEAD 请求返回根目录下的 index 文件(默认为 index.html)，
+// 用于 history 模式的前端路由。excludePrefixes 下的请求仍然交给 Engine.NotFoundHandler，如 /api
+func WithSPAFallback(excludePrefixes ...string) StaticOption {
+	return func(s *staticHandler) {
+		s.spa = true
+		s.spaExclude = excludePrefixes
+	}
+}
+
+// Static 把 dir 目录下的文件挂载到 prefix 下，请求路径不能访问 dir 之外的文件
+//
+//	e.Static("/assets", "./public")
+func (g *RouterGroup) Static(prefix string, dir string, opts ...StaticOption) IRouterGroup {
+	return g.StaticFS(prefix, os.DirFS(dir), opts...)
+}
+
+// StaticFS 把 fsys 中的文件挂载到 prefix 下，可以配合 embed.FS 把静态文件打包进二进制
+// 支持 Last-Modified、ETag 条件请求和 Range 请求，找不到文件时交给 Engine.NotFoundHandler 处理
+//
+//	//go:embed dist
+//	var dist embed.FS
+//	sub, _ := fs.Sub(dist, "dist")
+//	e.StaticFS("/", sub)
+func (g *RouterGroup) StaticFS(prefix string, fsys fs.FS, opts ...StaticOption) IRouterGroup {
+	s := &staticHandler{
+		engine: g.engine,
+		fs:     fsys,
+		index:  "index.html",
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	entry := routeEntry{handlers: []HandleFunc{s.serve}}
+	if s.spa {
+		// 排除的前缀下其他方法的请求返回 404 而不是 405
+		entry.skipAllow = s.excluded
+	}
+	// HEAD 请求使用 GET 的处理链
+	return g.addRoute(http.MethodGet, path.Join(prefix, "*"+staticParam), entry)
+}
+
+func (s *staticHandler) serve(ctx *Context) {
+	rest, _ := ctx.PathParams.Get(staticParam)
+	// 以 / 为根清理后的路径不会包含 ..，fs.FS 也会拒绝不合法的路径
+	name := strings.TrimPrefix(path.Clean("/"+rest), "/")
+	if name == "" {
+		name = "."
+	}
+
+	f, info, ok := s.open(name)
+	if !ok {
+		s.notFound(ctx)
+		return
+	}
+	defer f.Close()
+
+	if info.IsDir() {
+		if !strings.HasSuffix(ctx.Req.URL.Path, "/") {
+			// 目录以 / 结尾，页面中的相对路径才能正确解析
+			// 和 net/http 一样使用相对路径，//evil.com/../assets 这样的请求路径不会重定向到其他域名
+			target := (&url.URL{Path: "./" + path.Base(ctx.Req.URL.Path) + "/"}).EscapedPath()
+			if ctx.Req.URL.RawQuery != "" {
+				target += "?" + ctx.Req.URL.RawQuery
+			}
+			ctx.Resp.Header().Set("Location", target)
+			ctx.Status(http.StatusMovedPermanently)
+			return
+		}
+		if s.index != "" {
+			indexName := path.Join(name, s.index)
+			if index, indexInfo, ok := s.open(indexName); ok && !indexInfo.IsDir() {
+				defer index.Close()
+				s.serveFile(ctx, indexName, index, indexInfo)
+				return
+			}
+		}
+		if s.listing {
+			s.serveDir(ctx, name)
+			return
+		}
+		s.notFound(ctx)
+		return
+	}
+	s.serveFile(ctx, name, f, info)
+}
+
+// notFound 开启 WithSPAFallback 时尽量返回 index 文件，否则交给 Engine.NotFoundHandler
+func (s *staticHandler) notFound(ctx *Context) {
+	if !s.acceptFallback(ctx.Req) {
+		s.engine.NotFoundHandler(ctx)
+		return
+	}
+	index := s.index
+	if index == "" {
+		index = "index.html"
+	}
+	f, info, ok := s.open(index)
+	if !ok || info.IsDir() {
+		s.engine.NotFoundHandler(ctx)
+		return
+	}
+	defer f.Close()
+	// index 随前端版本变化，不能被缓存
+	ctx.Resp.Header().Set("Cache-Control", "no-cache")
+	s.serveFile(ctx, index, f, info)
+}
+
+func (s *staticHandler) acceptFallback(req *http.Request) bool {
+	if !s.spa || req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	return strings.Contains(req.Header.Get("Accept"), "text/html") && !s.excluded(req.URL.Path)
+}
+
+// excluded 请求路径是否在 WithSPAFallback 排除的前缀下
+func (s *staticHandler) excluded(p string) bool {
+	for _, prefix := range s.spaExclude {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if p == prefix || strings.HasPrefix(p, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *staticHandler) open(name string) (fs.File, fs.FileInfo, bool) {
+	f, err := s.fs.Open(name)
+	if err != nil {
+		return nil, nil, false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, nil, false
+	}
+	return f, info, true
+}
+
+// serveFile 由 http.ServeContent 处理 Content-Type、条件请求和 Range 请求
+// 文件内容直接写入客户端，不经过 Context.RespData 缓存
+func (s *staticHandler) serveFile(ctx *Context, name string, f fs.File, info fs.FileInfo) {
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			ctx.Status(http.StatusInternalServerError)
+			return
+		}
+		content = bytes.NewReader(data)
+	}
+
+	etag, err := s.etag(name, info, content)
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	header := ctx.Resp.Header()
+	header.Set("ETag", etag)
+	if s.maxAge > 0 && header.Get("Cache-Control") == "" {
+		header.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(s.maxAge.Seconds())))
+	}
+	http.ServeContent(ctx.Writer(), ctx.Req, info.Name(), info.ModTime(), content)
+}
+
+// etag 返回文件的 ETag，embed.FS 中的文件不会变化，根据内容生成的 ETag 只计算一次
+func (s *staticHandler) etag(name string, info fs.FileInfo, content io.ReadSeeker) (string, error) {
+	if !info.ModTime().IsZero() {
+		return fileETag(info, content)
+	}
+	if etag, ok := s.etags.Load(name); ok {
+		return etag.(string), nil
+	}
+	etag, err := fileETag(info, content)
+	if err != nil {
+		return "", err
+	}
+	s.etags.Store(name, etag)
+	return etag, nil
+}
+
+// fileETag 根据文件大小和修改时间生成弱 ETag
+// embed.FS 中的文件没有修改时间，此时根据文件内容生成
+func fileETag(info fs.FileInfo, content io.ReadSeeker) (string, error) {
+	if !info.ModTime().IsZero() {
+		return fmt.Sprintf(`W/"%x-%x"`, info.Size(), info.ModTime().UnixNano()), nil
+	}
+	h := fnv.New64a()
+	if _, err := io.Copy(h, content); err != nil {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`W/"%x-%x"`, info.Size(), h.Sum64()), nil
+}
+
+// serveDir 以html列出目录内容
+func (s *staticHandler) serveDir(ctx *Context, name string) {
+	entries, err := fs.ReadDir(s.fs, name)
+	if err != nil {
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+	var sb strings.Builder
+	sb.WriteString("<!doctype html>\n<meta name=\"viewport\" content=\"width=device-width\">\n<pre>\n")
+	for _, entry := range entries {
+		entryName := entry.Name()
+		if entry.IsDir() {
+			entryName += "/"
+		}
+		u := url.URL{Path: entryName}
+		_, _ = fmt.Fprintf(&sb, "<a href=\"%s\">%s</a>\n", u.String(), html.EscapeString(entryName))
+	}
+	sb.WriteString("</pre>\n")
+	_ = ctx.HTML(http.StatusOK, sb.String())
+}
@@ -0,0 +1,362 @@
+package web
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestRouterGroup_StaticFS(t *testing.T) {
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"index.html":      {Data: []byte("<h1>home</h1>"), ModTime: modTime},
+		"css/app.css":     {Data: []byte("body{}"), ModTime: modTime},
+		"js/app.js":       {Data: []byte("console.log(1)")},
+		"docs/readme.txt": {Data: []byte("0123456789"), ModTime: modTime},
+	}
+	e := NewEngine()
+	e.StaticFS("/assets", fsys, WithStaticMaxAge(time.Hour))
+	e.StaticFS("/list", fsys, WithStaticIndex(""), WithStaticListing(true))
+	etag := fmt.Sprintf(`W/"a-%x"`, modTime.UnixNano())
+
+	testCases := []struct {
+		name       string
+		method     string
+		path       string
+		header     map[string]string
+		wantStatus int
+		wantBody   string
+		wantHeader map[string]string
+	}{
+		{
+			name:       "file",
+			method:     http.MethodGet,
+			path:       "/assets/css/app.css",
+			wantStatus: http.StatusOK,
+			wantBody:   "body{}",
+			wantHeader: map[string]string{
+				"Content-Type":  "text/css; charset=utf-8",
+				"Last-Modified": modTime.Format(http.TimeFormat),
+				"Cache-Control": "public, max-age=3600",
+			},
+		},
+		{
+			name:       "index",
+			method:     http.MethodGet,
+			path:       "/assets/",
+			wantStatus: http.StatusOK,
+			wantBody:   "<h1>home</h1>",
+			wantHeader: map[string]string{
+				"Content-Type": "text/html; charset=utf-8",
+			},
+		},
+		{
+			name:       "directory redirect",
+			method:     http.MethodGet,
+			path:       "/assets/docs?a=1",
+			wantStatus: http.StatusMovedPermanently,
+			wantHeader: map[string]string{
+				"Location": "./docs/?a=1",
+			},
+		},
+		{
+			name:       "directory without index",
+			method:     http.MethodGet,
+			path:       "/assets/docs/",
+			wantStatus: http.StatusNotFound,
+			wantBody:   "404 page not found",
+		},
+		{
+			name:       "directory listing",
+			method:     http.MethodGet,
+			path:       "/list/",
+			wantStatus: http.StatusOK,
+			wantBody: "<!doctype html>\n<meta name=\"viewport\" content=\"width=device-width\">\n<pre>\n" +
+				"<a href=\"css/\">css/</a>\n<a href=\"docs/\">docs/</a>\n<a href=\"index.html\">index.html</a>\n<a href=\"js/\">js/</a>\n</pre>\n",
+		},
+		{
+			name:       "not found",
+			method:     http.MethodGet,
+			path:       "/assets/missing.js",
+			wantStatus: http.StatusNotFound,
+			wantBody:   "404 page not found",
+		},
+		{
+			name:       "path traversal",
+			method:     http.MethodGet,
+			path:       "/assets/css/../../../etc/passwd",
+			wantStatus: http.StatusNotFound,
+			wantBody:   "404 page not found",
+		},
+		{
+			name:       "etag",
+			method:     http.MethodGet,
+			path:       "/assets/docs/readme.txt",
+			wantStatus: http.StatusOK,
+			wantBody:   "0123456789",
+			wantHeader: map[string]string{
+				"ETag": etag,
+			},
+		},
+		{
+			name:       "if none match",
+			method:     http.MethodGet,
+			path:       "/assets/docs/readme.txt",
+			header:     map[string]string{"If-None-Match": etag},
+			wantStatus: http.StatusNotModified,
+		},
+		{
+			name:       "if modified since",
+			method:     http.MethodGet,
+			path:       "/assets/docs/readme.txt",
+			header:     map[string]string{"If-Modified-Since": modTime.Format(http.TimeFormat)},
+			wantStatus: http.StatusNotModified,
+		},
+		{
+			name:       "range",
+			method:     http.MethodGet,
+			path:       "/assets/docs/readme.txt",
+			header:     map[string]string{"Range": "bytes=2-5"},
+			wantStatus: http.StatusPartialContent,
+			wantBody:   "2345",
+			wantHeader: map[string]string{
+				"Content-Range": "bytes 2-5/10",
+			},
+		},
+		{
+			name:       "head",
+			method:     http.MethodHead,
+			path:       "/assets/docs/readme.txt",
+			wantStatus: http.StatusOK,
+			wantHeader: map[string]string{
+				"Content-Length": "10",
+			},
+		},
+		{
+			name:       "etag from content without mod time",
+			method:     http.MethodGet,
+			path:       "/assets/js/app.js",
+			wantStatus: http.StatusOK,
+			wantBody:   "console.log(1)",
+			wantHeader: map[string]string{
+				"Content-Type": "text/javascript; charset=utf-8",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			for k, v := range tc.header {
+				req.Header.Set(k, v)
+			}
+			recorder := httptest.NewRecorder()
+			e.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantStatus, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+			for k, v := range tc.wantHeader {
+				assert.Equal(t, v, recorder.Header().Get(k), k)
+			}
+		})
+	}
+}
+
+func TestRouterGroup_StaticFS_etagCache(t *testing.T) {
+	fsys := fstest.MapFS{"app.js": {Data: []byte("console.log(1)")}}
+	e := NewEngine()
+	e.StaticFS("/", fsys)
+	serve := func() *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/app.js", nil))
+		return recorder
+	}
+
+	etag := serve().Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	// 没有修改时间的文件视为不会变化，不再读取内容计算 ETag
+	fsys["app.js"] = &fstest.MapFile{Data: []byte("console.log(2)")}
+	recorder := serve()
+	assert.Equal(t, etag, recorder.Header().Get("ETag"))
+	assert.Equal(t, "console.log(2)", recorder.Body.String())
+
+	// 有修改时间的文件每次都根据修改时间生成
+	fsys["app.js"] = &fstest.MapFile{Data: []byte("console.log(3)"), ModTime: time.Unix(1, 0)}
+	assert.NotEqual(t, etag, serve().Header().Get("ETag"))
+}
+
+func TestRouterGroup_StaticFS_redirect(t *testing.T) {
+	e := NewEngine()
+	e.StaticFS("/", fstest.MapFS{
+		"docs/readme.txt":    {Data: []byte("readme")},
+		"my docs/readme.txt": {Data: []byte("readme")},
+	})
+
+	testCases := []struct {
+		path         string
+		wantLocation string
+	}{
+		{path: "/docs", wantLocation: "./docs/"},
+		{path: "//evil.com/../docs", wantLocation: "./docs/"},
+		{path: "/my%20docs", wantLocation: "./my%20docs/"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			e.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			assert.Equal(t, http.StatusMovedPermanently, recorder.Code)
+			assert.Equal(t, tc.wantLocation, recorder.Header().Get("Location"))
+		})
+	}
+}
+
+func TestRouterGroup_Static(t *testing.T) {
+	root := t.TempDir()
+	public := filepath.Join(root, "public")
+	require.NoError(t, os.Mkdir(public, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(public, "app.txt"), []byte("app"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "secret.txt"), []byte("secret"), 0o644))
+
+	e := NewEngine()
+	e.Group("/static").Static("/", public)
+
+	for path, wantStatus := range map[string]int{
+		"/static/app.txt":           http.StatusOK,
+		"/static/../secret.txt":     http.StatusNotFound,
+		"/static/%2e%2e/secret.txt": http.StatusNotFound,
+	} {
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, wantStatus, recorder.Code, path)
+		assert.NotContains(t, recorder.Body.String(), "secret", path)
+	}
+
+	routes := e.Routes()
+	require.Len(t, routes, 1)
+	assert.Equal(t, "/static/*filepath", routes[0].Path)
+}
+
+func TestWithSPAFallback(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":  {Data: []byte("<div id=app></div>")},
+		"js/app.js":   {Data: []byte("console.log(1)")},
+		"favicon.ico": {Data: []byte("ico")},
+	}
+	e := NewEngine(WithNotFoundHandler(func(ctx *Context) {
+		_ = ctx.JSON(http.StatusNotFound, map[string]string{"error": "not found"})
+	}))
+	e.GET("/api/users", userHandler)
+	e.StaticFS("/", fsys, WithSPAFallback("/api/"), WithStaticMaxAge(time.Hour))
+
+	const html = "text/html,application/xhtml+xml,*/*;q=0.8"
+	testCases := []struct {
+		name       string
+		method     string
+		path       string
+		accept     string
+		wantStatus int
+		wantBody   string
+		wantCache  string
+	}{
+		{
+			name:       "client route",
+			method:     http.MethodGet,
+			path:       "/users/1/profile",
+			accept:     html,
+			wantStatus: http.StatusOK,
+			wantBody:   "<div id=app></div>",
+			wantCache:  "no-cache",
+		},
+		{
+			name:       "existing file",
+			method:     http.MethodGet,
+			path:       "/js/app.js",
+			accept:     html,
+			wantStatus: http.StatusOK,
+			wantBody:   "console.log(1)",
+			wantCache:  "public, max-age=3600",
+		},
+		{
+			name:       "missing asset",
+			method:     http.MethodGet,
+			path:       "/js/missing.js",
+			accept:     "*/*",
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"error":"not found"}`,
+		},
+		{
+			name:       "api prefix",
+			method:     http.MethodGet,
+			path:       "/api/orders",
+			accept:     html,
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"error":"not found"}`,
+		},
+		{
+			name:       "api route",
+			method:     http.MethodGet,
+			path:       "/api/users",
+			accept:     html,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "not under api prefix",
+			method:     http.MethodGet,
+			path:       "/apis",
+			accept:     html,
+			wantStatus: http.StatusOK,
+			wantBody:   "<div id=app></div>",
+			wantCache:  "no-cache",
+		},
+		{
+			name:       "post under api prefix",
+			method:     http.MethodPost,
+			path:       "/api/missing",
+			accept:     "application/json",
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"error":"not found"}`,
+		},
+		{
+			name:       "delete under api prefix",
+			method:     http.MethodDelete,
+			path:       "/api/missing",
+			accept:     "application/json",
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"error":"not found"}`,
+		},
+		{
+			name:       "post to api route",
+			method:     http.MethodPost,
+			path:       "/api/users",
+			accept:     "application/json",
+			wantStatus: http.StatusMethodNotAllowed,
+			wantBody:   "405 method not allowed",
+		},
+		{
+			name:       "head",
+			method:     http.MethodHead,
+			path:       "/settings",
+			accept:     html,
+			wantStatus: http.StatusOK,
+			wantCache:  "no-cache",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			req.Header.Set("Accept", tc.accept)
+			recorder := httptest.NewRecorder()
+			e.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantStatus, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+			assert.Equal(t, tc.wantCache, recorder.Header().Get("Cache-Control"))
+		})
+	}
+}
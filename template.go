@@ -0,0 +1,238 @@
+package web
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrTemplatesNotConfigured Engine 没有通过 WithTemplates 或 WithTemplateDir 配置模板
+	ErrTemplatesNotConfigured = errors.New("templates not configured")
+	ErrTemplateNotFound       = errors.New("template not found")
+)
+
+// Templates 从 fs.FS 加载 html/template 模板，每个页面和所有布局、公共片段组成一个模板集合
+//
+// 布局目录(默认 layouts)和片段目录(默认 partials)之外的文件都是页面，页面名是相对根目录的路径，如 users/show.html
+// 布局中使用 {{template "content" .}} 引用页面内容，页面中定义了 content 时使用布局渲染，否则直接渲染页面本身
+type Templates struct {
+	fs         fs.FS
+	ext        string
+	layoutDir  string
+	partialDir string
+	// 默认使用的布局，为空时不使用布局
+	layout string
+	funcs  template.FuncMap
+	// 每次渲染前重新加载模板，只用于开发环境
+	reload bool
+
+	mu    sync.RWMutex
+	pages map[string]*template.Template
+}
+
+type TemplateOption func(t *Templates)
+
+// WithTemplateExt 设置模板文件的扩展名，默认为 .html
+func WithTemplateExt(ext string) TemplateOption {
+	return func(t *Templates) {
+		t.ext = ext
+	}
+}
+
+// WithTemplateLayoutDir 设置布局目录，默认为 layouts
+func WithTemplateLayoutDir(dir string) TemplateOption {
+	return func(t *Templates) {
+		t.layoutDir = dir
+	}
+}
+
+// WithTemplatePartialDir 设置公共片段目录，默认为 partials，每个页面都可以引用其中定义的模板
+func WithTemplatePartialDir(dir string) TemplateOption {
+	return func(t *Templates) {
+		t.partialDir = dir
+	}
+}
+
+// WithTemplateLayout 设置页面默认使用的布局，如 layouts/base.html
+func WithTemplateLayout(name string) TemplateOption {
+	return func(t *Templates) {
+		t.layout = name
+	}
+}
+
+// WithTemplateFuncs 添加模板函数，同名时覆盖内置的 url 函数
+func WithTemplateFuncs(funcs template.FuncMap) TemplateOption {
+	return func(t *Templates) {
+		for name, fn := range funcs {
+			t.funcs[name] = fn
+		}
+	}
+}
+
+// WithTemplateReload 每次渲染前重新从文件加载模板，修改模板后不需要重启，只用于开发环境
+func WithTemplateReload(reload bool) TemplateOption {
+	return func(t *Templates) {
+		t.reload = reload
+	}
+}
+
+// NewTemplates 加载 fsys 中的模板，模板语法错误时返回错误
+func NewTemplates(fsys fs.FS, opts ...TemplateOption) (*Templates, error) {
+	t := &Templates{
+		fs:         fsys,
+		ext:        ".html",
+		layoutDir:  "layouts",
+		partialDir: "partials",
+		funcs:      template.FuncMap{},
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	if err := t.load(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// WithTemplates 加载 fsys 中的模板用于 Context.Render，模板中可以使用 url 函数根据路由名生成 url
+// 模板语法错误时 panic
+//
+//	//go:embed views
+//	var views embed.FS
+//	sub, _ := fs.Sub(views, "views")
+//	e := web.NewEngine(web.WithTemplates(sub, web.WithTemplateLayout("layouts/base.html")))
+//
+//	<a href="{{url "user.show" "id" .ID}}">{{.Name}}</a>
+func WithTemplates(fsys fs.FS, opts ...TemplateOption) EngineOption {
+	return func(e *Engine) {
+		// url 放在最前面，可以被 WithTemplateFuncs 覆盖
+		// 同一个 EngineOption 可能用于多个 Engine，不能修改 opts
+		engineOpts := make([]TemplateOption, 0, len(opts)+1)
+		engineOpts = append(engineOpts, WithTemplateFuncs(template.FuncMap{"url": urlFunc(e)}))
+		engineOpts = append(engineOpts, opts...)
+		t, err := NewTemplates(fsys, engineOpts...)
+		if err != nil {
+			panic(err)
+		}
+		e.Templates = t
+	}
+}
+
+// urlFunc 模板中的参数值可以是数字等任意类型
+func urlFunc(e *Engine) func(name string, pairs ...any) (string, error) {
+	return func(name string, pairs ...any) (string, error) {
+		strs := make([]string, len(pairs))
+		for i, p := range pairs {
+			strs[i] = fmt.Sprint(p)
+		}
+		return e.URL(name, strs...)
+	}
+}
+
+// WithTemplateDir 加载 dir 目录下的模板，见 WithTemplates
+func WithTemplateDir(dir string, opts ...TemplateOption) EngineOption {
+	return WithTemplates(os.DirFS(dir), opts...)
+}
+
+// load 解析所有模板，成功后替换已加载的模板
+func (t *Templates) load() error {
+	var shared, pages []string
+	err := fs.WalkDir(t.fs, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(p) != t.ext {
+			return err
+		}
+		if inDir(p, t.layoutDir) || inDir(p, t.partialDir) {
+			shared = append(shared, p)
+		} else {
+			pages = append(pages, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	base := template.New("").Funcs(t.funcs)
+	for _, p := range shared {
+		if err = t.parse(base, p); err != nil {
+			return err
+		}
+	}
+	if t.layout != "" && base.Lookup(t.layout) == nil {
+		return fmt.Errorf("%w: layout %s", ErrTemplateNotFound, t.layout)
+	}
+	res := make(map[string]*template.Template, len(pages))
+	for _, p := range pages {
+		page, err := base.Clone()
+		if err != nil {
+			return err
+		}
+		if err = t.parse(page, p); err != nil {
+			return err
+		}
+		res[p] = page
+	}
+
+	t.mu.Lock()
+	t.pages = res
+	t.mu.Unlock()
+	return nil
+}
+
+func (t *Templates) parse(set *template.Template, name string) error {
+	data, err := fs.ReadFile(t.fs, name)
+	if err != nil {
+		return err
+	}
+	_, err = set.New(name).Parse(string(data))
+	return err
+}
+
+func inDir(p string, dir string) bool {
+	return dir != "" && strings.HasPrefix(p, dir+"/")
+}
+
+// Execute 渲染页面 name 到 w，页面定义了 content 时使用默认布局
+func (t *Templates) Execute(w io.Writer, name string, data any) error {
+	if t.reload {
+		if err := t.load(); err != nil {
+			return err
+		}
+	}
+	t.mu.RLock()
+	page, ok := t.pages[name]
+	t.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+	if t.layout != "" && page.Lookup("content") != nil {
+		return page.ExecuteTemplate(w, t.layout, data)
+	}
+	return page.ExecuteTemplate(w, name, data)
+}
+
+// Render 使用 Engine 配置的模板渲染页面 name，html/template 会根据上下文转义 data 中的内容
+// 渲染失败时不修改响应，直接返回错误
+//
+//	_ = ctx.Render(http.StatusOK, "users/show.html", user)
+func (c *Context) Render(status int, name string, data any) error {
+	if c.engine == nil || c.engine.Templates == nil {
+		return ErrTemplatesNotConfigured
+	}
+	var buf bytes.Buffer
+	if err := c.engine.Templates.Execute(&buf, name, data); err != nil {
+		return err
+	}
+	c.Resp.Header().Set("Content-Type", "text/html; charset=utf-8")
+	c.StatusCode = status
+	c.RespData = buf.Bytes()
+	return nil
+}
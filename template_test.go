@@ -0,0 +1,144 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func newTemplateFS() fstest.MapFS {
+	return fstest.MapFS{
+		"layouts/base.html": {Data: []byte(`<html><title>{{block "title" .}}site{{end}}</title>` +
+			`<body>{{template "content" .}}</body></html>`)},
+		"partials/user.html": {Data: []byte(`{{define "user"}}<a href="{{url "user.show" "id" .ID}}">{{.Name}}</a>{{end}}`)},
+		"users/show.html": {Data: []byte(`{{define "title"}}{{.Name}}{{end}}` +
+			`{{define "content"}}{{template "user" .}}{{end}}`)},
+		"plain.html": {Data: []byte(`<p>{{upper .Name}}</p>`)},
+		"notes.txt":  {Data: []byte(`{{ not parsed`)},
+	}
+}
+
+func TestContext_Render(t *testing.T) {
+	type user struct {
+		ID   int
+		Name string
+	}
+	testCases := []struct {
+		name     string
+		page     string
+		data     any
+		wantCode int
+		wantBody string
+		wantErr  error
+	}{
+		{
+			name:     "layout",
+			page:     "users/show.html",
+			data:     user{ID: 42, Name: "tom"},
+			wantCode: http.StatusOK,
+			wantBody: `<html><title>tom</title><body><a href="/users/42">tom</a></body></html>`,
+		},
+		{
+			name:     "escape",
+			page:     "users/show.html",
+			data:     user{ID: 1, Name: "<script>alert(1)</script>"},
+			wantCode: http.StatusOK,
+			wantBody: `<html><title>&lt;script&gt;alert(1)&lt;/script&gt;</title>` +
+				`<body><a href="/users/1">&lt;script&gt;alert(1)&lt;/script&gt;</a></body></html>`,
+		},
+		{
+			name:     "without layout",
+			page:     "plain.html",
+			data:     user{Name: "tom"},
+			wantCode: http.StatusOK,
+			wantBody: `<p>TOM</p>`,
+		},
+		{
+			name:     "not found",
+			page:     "users/list.html",
+			wantCode: http.StatusOK,
+			wantErr:  ErrTemplateNotFound,
+		},
+	}
+
+	e := NewEngine(WithTemplates(newTemplateFS(),
+		WithTemplateLayout("layouts/base.html"),
+		WithTemplateFuncs(template.FuncMap{"upper": strings.ToUpper})))
+	e.GET("/users/:id", mockHandler).Name("user.show")
+
+	for i, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := "/render/" + strconv.Itoa(i)
+			e.GET(path, func(ctx *Context) {
+				err := ctx.Render(http.StatusOK, tc.page, tc.data)
+				assert.ErrorIs(t, err, tc.wantErr)
+			})
+			recorder := httptest.NewRecorder()
+			e.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+			if tc.wantErr == nil {
+				assert.Equal(t, "text/html; charset=utf-8", recorder.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestWithTemplates_multipleEngines(t *testing.T) {
+	opt := WithTemplates(fstest.MapFS{"index.html": {Data: []byte(`{{url "home"}}`)}})
+	e1 := NewEngine(opt)
+	e1.GET("/one", mockHandler).Name("home")
+	e2 := NewEngine(opt)
+	e2.GET("/two", mockHandler).Name("home")
+
+	for e, want := range map[*Engine]string{e1: "/one", e2: "/two"} {
+		var sb strings.Builder
+		require.NoError(t, e.Templates.Execute(&sb, "index.html", nil))
+		assert.Equal(t, want, sb.String())
+	}
+}
+
+func TestTemplates_reload(t *testing.T) {
+	fsys := fstest.MapFS{"index.html": {Data: []byte(`<p>{{.}}</p>`)}}
+	static, err := NewTemplates(fsys)
+	require.NoError(t, err)
+	reload, err := NewTemplates(fsys, WithTemplateReload(true))
+	require.NoError(t, err)
+
+	fsys["index.html"] = &fstest.MapFile{Data: []byte(`<div>{{.}}</div>`)}
+	var sb strings.Builder
+	require.NoError(t, static.Execute(&sb, "index.html", "a"))
+	assert.Equal(t, "<p>a</p>", sb.String())
+	sb.Reset()
+	require.NoError(t, reload.Execute(&sb, "index.html", "a"))
+	assert.Equal(t, "<div>a</div>", sb.String())
+
+	// 重新加载失败时返回错误
+	fsys["index.html"] = &fstest.MapFile{Data: []byte(`{{.`)}
+	assert.Error(t, reload.Execute(&sb, "index.html", "a"))
+}
+
+func TestNewTemplates_error(t *testing.T) {
+	_, err := NewTemplates(fstest.MapFS{"index.html": {Data: []byte(`{{if}}`)}})
+	assert.Error(t, err)
+
+	_, err = NewTemplates(fstest.MapFS{"index.html": {Data: []byte(`ok`)}}, WithTemplateLayout("layouts/base.html"))
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+
+	// 没有注册 url 函数
+	_, err = NewTemplates(newTemplateFS())
+	assert.Error(t, err)
+
+	assert.Panics(t, func() {
+		NewEngine(WithTemplates(fstest.MapFS{"index.html": {Data: []byte(`{{.`)}}))
+	})
+
+	ctx := newContext(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.ErrorIs(t, ctx.Render(http.StatusOK, "index.html", nil), ErrTemplatesNotConfigured)
+}
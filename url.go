@@ -0,0 +1,75 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+var (
+	ErrRouteNameNotFound = errors.New("route name not found")
+	ErrInvalidURLParams  = errors.New("invalid url params")
+)
+
+// URL 根据路由名和路径参数生成url，pairs 依次为参数名和参数值
+// 参数缺失、多余或者不满足路径参数约束时返回 ErrInvalidURLParams
+//
+//	e.GET("/users/:id", handler).Name("user.show")
+//	e.URL("user.show", "id", "42") // /users/42
+func (e *Engine) URL(name string, pairs ...string) (string, error) {
+	key, ok := e.load().names[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrRouteNameNotFound, name)
+	}
+	if len(pairs)%2 != 0 {
+		return "", fmt.Errorf("%w: odd number of params", ErrInvalidURLParams)
+	}
+
+	params := make(map[string]string, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		params[pairs[i]] = pairs[i+1]
+	}
+
+	var sb strings.Builder
+	for _, seg := range splitPath(key.route) {
+		sb.WriteByte('/')
+		switch {
+		case isCatchAll(seg):
+			val, ok := params[seg[1:]]
+			if !ok {
+				return "", fmt.Errorf("%w: missing param %s", ErrInvalidURLParams, seg[1:])
+			}
+			delete(params, seg[1:])
+			// *name 的值可以包含多段路径，逐段转义
+			parts := strings.Split(strings.TrimPrefix(val, "/"), "/")
+			for i, part := range parts {
+				parts[i] = url.PathEscape(part)
+			}
+			sb.WriteString(strings.Join(parts, "/"))
+		case seg == "*":
+			return "", fmt.Errorf("%w: can't build url for wildcard segment", ErrInvalidURLParams)
+		case seg[0] == ':':
+			key, constraint := parseParam(seg)
+			val, ok := params[key]
+			if !ok {
+				return "", fmt.Errorf("%w: missing param %s", ErrInvalidURLParams, key)
+			}
+			if val == "" || constraint != nil && !constraint.MatchString(val) {
+				return "", fmt.Errorf("%w: param %s doesn't match %s", ErrInvalidURLParams, key, seg)
+			}
+			delete(params, key)
+			sb.WriteString(url.PathEscape(val))
+		default:
+			sb.WriteString(seg)
+		}
+	}
+
+	for key := range params {
+		return "", fmt.Errorf("%w: unknown param %s", ErrInvalidURLParams, key)
+	}
+	if sb.Len() == 0 || strings.HasSuffix(key.route, "/") {
+		sb.WriteByte('/')
+	}
+	return sb.String(), nil
+}
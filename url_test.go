@@ -0,0 +1,103 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEngine_URL(t *testing.T) {
+	e := NewEngine()
+	e.GET("/", mockHandler).Name("home")
+	users := e.Group("/users")
+	users.GET("/:id<int>", mockHandler).Name("user.show")
+	users.GET("/:id<int>/posts/:slug", mockHandler).Name("user.post")
+	e.GET("/static/*filepath", mockHandler).Name("static")
+	e.GET("/post/*/detail", mockHandler).Name("post.detail")
+	e.GET("/docs/", mockHandler).Name("docs")
+
+	testCases := []struct {
+		name    string
+		route   string
+		pairs   []string
+		wantURL string
+		wantErr error
+	}{
+		{
+			name:    "root",
+			route:   "home",
+			wantURL: "/",
+		},
+		{
+			name:    "group prefix",
+			route:   "user.show",
+			pairs:   []string{"id", "42"},
+			wantURL: "/users/42",
+		},
+		{
+			name:    "escape",
+			route:   "user.post",
+			pairs:   []string{"id", "42", "slug", "hello world/?"},
+			wantURL: "/users/42/posts/hello%20world%2F%3F",
+		},
+		{
+			name:    "catch-all keeps slashes",
+			route:   "static",
+			pairs:   []string{"filepath", "css/main app.css"},
+			wantURL: "/static/css/main%20app.css",
+		},
+		{
+			name:    "keep trailing slash",
+			route:   "docs",
+			wantURL: "/docs/",
+		},
+		{
+			name:    "missing param",
+			route:   "user.post",
+			pairs:   []string{"id", "42"},
+			wantErr: ErrInvalidURLParams,
+		},
+		{
+			name:    "extra param",
+			route:   "user.show",
+			pairs:   []string{"id", "42", "page", "1"},
+			wantErr: ErrInvalidURLParams,
+		},
+		{
+			name:    "constraint mismatch",
+			route:   "user.show",
+			pairs:   []string{"id", "tom"},
+			wantErr: ErrInvalidURLParams,
+		},
+		{
+			name:    "odd pairs",
+			route:   "user.show",
+			pairs:   []string{"id"},
+			wantErr: ErrInvalidURLParams,
+		},
+		{
+			name:    "wildcard",
+			route:   "post.detail",
+			wantErr: ErrInvalidURLParams,
+		},
+		{
+			name:    "unknown route",
+			route:   "order.show",
+			wantErr: ErrRouteNameNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			url, err := e.URL(tc.route, tc.pairs...)
+			assert.ErrorIs(t, err, tc.wantErr)
+			assert.Equal(t, tc.wantURL, url)
+		})
+	}
+
+	assert.Panicsf(t, func() {
+		e.POST("/users", mockHandler).Name("user.show")
+	}, "duplicated route name: user.show")
+	assert.Panicsf(t, func() {
+		e.Group("/order").Name("order")
+	}, "no route to name")
+}
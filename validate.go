@@ -0,0 +1,273 @@
+package web
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// ValidationRule 校验规则，param 是规则 = 后面的部分，如 min=1 中的 1，返回 false 表示校验失败
+// 字段是指针时，val 是指针指向的值，nil 指针只会执行 required 规则
+type ValidationRule func(val reflect.Value, param string) bool
+
+var validationRules = map[string]ValidationRule{
+	"required": func(val reflect.Value, _ string) bool {
+		return !val.IsZero()
+	},
+	"min": sizeRule(func(size, param float64) bool { return size >= param }),
+	"max": sizeRule(func(size, param float64) bool { return size <= param }),
+	"len": sizeRule(func(size, param float64) bool { return size == param }),
+	"gt":  sizeRule(func(size, param float64) bool { return size > param }),
+	"gte": sizeRule(func(size, param float64) bool { return size >= param }),
+	"lt":  sizeRule(func(size, param float64) bool { return size < param }),
+	"lte": sizeRule(func(size, param float64) bool { return size <= param }),
+	"oneof": func(val reflect.Value, param string) bool {
+		s, ok := scalarString(val)
+		if !ok {
+			return false
+		}
+		for _, opt := range strings.Fields(param) {
+			if s == opt {
+				return true
+			}
+		}
+		return false
+	},
+	"email": func(val reflect.Value, _ string) bool {
+		if val.Kind() != reflect.String {
+			return false
+		}
+		addr, err := mail.ParseAddress(val.String())
+		return err == nil && addr.Address == val.String()
+	},
+}
+
+// sizeRules 参数必须是数字的规则，解析 tag 时检查
+var sizeRules = map[string]bool{"min": true, "max": true, "len": true, "gt": true, "gte": true, "lt": true, "lte": true}
+
+// RegisterValidation 注册自定义校验规则，同名时覆盖内置规则，需要在校验之前调用
+//
+//	web.RegisterValidation("mobile", func(val reflect.Value, _ string) bool {
+//		return mobileRegexp.MatchString(val.String())
+//	})
+func RegisterValidation(name string, rule ValidationRule) {
+	validationRules[name] = rule
+}
+
+// ValidationError 单个字段没有通过的规则
+type ValidationError struct {
+	// Field 结构体中的字段名，嵌套的字段用 . 连接，切片元素带下标，如 Items[0].Name
+	Field string
+	Rule  string
+	Param string
+	Value any
+}
+
+func (e *ValidationError) Error() string {
+	if e.Param == "" {
+		return fmt.Sprintf("field %s failed on rule %s", e.Field, e.Rule)
+	}
+	return fmt.Sprintf("field %s failed on rule %s=%s", e.Field, e.Rule, e.Param)
+}
+
+// ValidationErrors 所有没有通过校验的字段
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, ve := range e {
+		msgs[i] = ve.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e ValidationErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, ve := range e {
+		errs[i] = ve
+	}
+	return errs
+}
+
+// Validate 按 validate tag 校验结构体，会递归校验嵌套的结构体以及结构体切片的每个元素
+// 规则之间用逗号分隔，如 `validate:"required,min=1,max=100"`、`validate:"omitempty,email"`、`validate:"oneof=a b"`
+// omitempty 表示零值时跳过其余规则，validate:"-" 表示不校验该字段及其嵌套的字段
+// 校验失败时返回 ValidationErrors
+func Validate(val any) error {
+	rv := reflect.ValueOf(val)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	var errs ValidationErrors
+	validateStruct(rv, "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+type validateRule struct {
+	name  string
+	param string
+	fn    ValidationRule
+}
+
+type validateField struct {
+	index     int
+	name      string
+	rules     []validateRule
+	omitempty bool
+}
+
+var validateFieldsCache sync.Map
+
+func cachedValidateFields(typ reflect.Type) []validateField {
+	if fields, ok := validateFieldsCache.Load(typ); ok {
+		return fields.([]validateField)
+	}
+	fields := parseValidateFields(typ)
+	validateFieldsCache.Store(typ, fields)
+	return fields
+}
+
+// parseValidateFields 规则不存在或参数不合法时 panic
+func parseValidateFields(typ reflect.Type) []validateField {
+	var res []validateField
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		tag := sf.Tag.Get("validate")
+		// 未导出的嵌入结构体中的导出字段仍然可以访问
+		if (!sf.IsExported() && !sf.Anonymous) || tag == "-" {
+			continue
+		}
+		f := validateField{index: i, name: sf.Name}
+		for _, item := range strings.Split(tag, ",") {
+			name, param, _ := strings.Cut(strings.TrimSpace(item), "=")
+			switch name {
+			case "":
+				continue
+			case "omitempty":
+				f.omitempty = true
+				continue
+			}
+			fn, ok := validationRules[name]
+			if !ok {
+				panic(fmt.Sprintf("unknown validation rule: %s", name))
+			}
+			if sizeRules[name] {
+				if _, err := strconv.ParseFloat(param, 64); err != nil {
+					panic(fmt.Sprintf("invalid param for validation rule %s: %q", name, param))
+				}
+			}
+			f.rules = append(f.rules, validateRule{name: name, param: param, fn: fn})
+		}
+		res = append(res, f)
+	}
+	return res
+}
+
+func validateStruct(v reflect.Value, prefix string, errs *ValidationErrors) {
+	for _, f := range cachedValidateFields(v.Type()) {
+		fv := v.Field(f.index)
+		name := prefix + f.name
+		if f.omitempty && fv.IsZero() {
+			continue
+		}
+		if !validateValue(fv, name, f.rules, errs) {
+			continue
+		}
+		validateNested(fv, name, errs)
+	}
+}
+
+// validateValue 依次执行规则，第一个失败的规则记录为错误，返回是否全部通过
+func validateValue(v reflect.Value, name string, rules []validateRule, errs *ValidationErrors) bool {
+	for _, rule := range rules {
+		target := v
+		if v.Kind() == reflect.Pointer && rule.name != "required" {
+			if v.IsNil() {
+				continue
+			}
+			target = v.Elem()
+		}
+		if !rule.fn(target, rule.param) {
+			ve := &ValidationError{
+				Field: name,
+				Rule:  rule.name,
+				Param: rule.param,
+			}
+			if target.CanInterface() {
+				ve.Value = target.Interface()
+			}
+			*errs = append(*errs, ve)
+			return false
+		}
+	}
+	return true
+}
+
+// validateNested 校验结构体、结构体指针以及结构体切片中的字段
+func validateNested(v reflect.Value, name string, errs *ValidationErrors) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			validateNested(v.Elem(), name, errs)
+		}
+	case reflect.Struct:
+		if v.Type() != timeType {
+			validateStruct(v, name+".", errs)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			validateNested(v.Index(i), name+"["+strconv.Itoa(i)+"]", errs)
+		}
+	}
+}
+
+// sizeRule 字符串比较字符数，切片和 map 比较长度，数字比较数值
+func sizeRule(cmp func(size, param float64) bool) ValidationRule {
+	return func(val reflect.Value, param string) bool {
+		p, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return false
+		}
+		var size float64
+		switch val.Kind() {
+		case reflect.String:
+			size = float64(utf8.RuneCountInString(val.String()))
+		case reflect.Slice, reflect.Array, reflect.Map:
+			size = float64(val.Len())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			size = float64(val.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			size = float64(val.Uint())
+		case reflect.Float32, reflect.Float64:
+			size = val.Float()
+		default:
+			return false
+		}
+		return cmp(size, p)
+	}
+}
+
+func scalarString(val reflect.Value) (string, bool) {
+	switch val.Kind() {
+	case reflect.String:
+		return val.String(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(val.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(val.Uint(), 10), true
+	}
+	return "", false
+}
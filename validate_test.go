@@ -0,0 +1,219 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type orderItem struct {
+	SKU   string `json:"sku" validate:"required,len=6"`
+	Count int    `json:"count" validate:"gte=1,lte=99"`
+}
+
+type address struct {
+	City string `json:"city" validate:"required"`
+}
+
+type createOrderReq struct {
+	Email    string      `json:"email" validate:"required,email"`
+	Nickname string      `json:"nickname" validate:"omitempty,min=2,max=8"`
+	Channel  string      `json:"channel" validate:"oneof=web app"`
+	Tags     []string    `json:"tags" validate:"max=2"`
+	Items    []orderItem `json:"items" validate:"required"`
+	Address  *address    `json:"address"`
+	Coupon   *string     `json:"coupon" validate:"omitempty,min=4"`
+	Note     *string     `json:"note" validate:"required"`
+	Internal address     `json:"-" validate:"-"`
+}
+
+func TestValidate(t *testing.T) {
+	note := ""
+	short := "ab"
+	testCases := []struct {
+		name    string
+		val     any
+		wantErr []string
+	}{
+		{
+			name: "valid",
+			val: &createOrderReq{
+				Email:   "tom@example.com",
+				Channel: "app",
+				Items:   []orderItem{{SKU: "abc123", Count: 1}},
+				Address: &address{City: "Hangzhou"},
+				Note:    &note,
+			},
+		},
+		{
+			name: "invalid",
+			val: createOrderReq{
+				Email:    "Tom <tom@example.com>",
+				Nickname: "t",
+				Channel:  "mail",
+				Tags:     []string{"a", "b", "c"},
+				Items:    []orderItem{{SKU: "abc123", Count: 1}, {SKU: "abc", Count: 100}},
+				Address:  &address{},
+				Coupon:   &short,
+			},
+			wantErr: []string{
+				"field Email failed on rule email",
+				"field Nickname failed on rule min=2",
+				"field Channel failed on rule oneof=web app",
+				"field Tags failed on rule max=2",
+				"field Items[1].SKU failed on rule len=6",
+				"field Items[1].Count failed on rule lte=99",
+				"field Address.City failed on rule required",
+				"field Coupon failed on rule min=4",
+				"field Note failed on rule required",
+			},
+		},
+		{
+			name:    "missing items",
+			val:     &createOrderReq{Email: "tom@example.com", Channel: "web", Note: &note},
+			wantErr: []string{"field Items failed on rule required"},
+		},
+		{
+			name: "not struct",
+			val:  []int{1},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Validate(tc.val)
+			if len(tc.wantErr) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			var errs ValidationErrors
+			require.ErrorAs(t, err, &errs)
+			msgs := make([]string, len(errs))
+			for i, ve := range errs {
+				msgs[i] = ve.Error()
+			}
+			assert.Equal(t, tc.wantErr, msgs)
+		})
+	}
+}
+
+func TestRegisterValidation(t *testing.T) {
+	RegisterValidation("prefix", func(val reflect.Value, param string) bool {
+		return strings.HasPrefix(val.String(), param)
+	})
+	type req struct {
+		ID string `validate:"prefix=ord_"`
+	}
+	assert.NoError(t, Validate(req{ID: "ord_1"}))
+	var errs ValidationErrors
+	require.ErrorAs(t, Validate(req{ID: "1"}), &errs)
+	assert.Equal(t, &ValidationError{Field: "ID", Rule: "prefix", Param: "ord_", Value: "1"}, errs[0])
+
+	assert.PanicsWithValue(t, "unknown validation rule: unknown", func() {
+		_ = Validate(struct {
+			Name string `validate:"unknown"`
+		}{})
+	})
+	assert.PanicsWithValue(t, `invalid param for validation rule min: "x"`, func() {
+		_ = Validate(struct {
+			Name string `validate:"min=x"`
+		}{})
+	})
+}
+
+func TestContext_BindJSON_validate(t *testing.T) {
+	type pageReq struct {
+		Page int    `query:"page" validate:"min=1"`
+		Size int    `query:"size" validate:"max=100"`
+		Sort string `query:"sort" validate:"omitempty,oneof=asc desc"`
+	}
+	e := NewEngine()
+	e.POST("/orders", func(ctx *Context) {
+		var req createOrderReq
+		if err := ctx.BindJSON(&req); err != nil {
+			return
+		}
+		_ = ctx.JsonOK(req.Items)
+	})
+	e.GET("/orders", func(ctx *Context) {
+		req := pageReq{Page: 1, Size: 20}
+		if err := ctx.Bind(&req); err != nil {
+			return
+		}
+		ctx.StatusCode = http.StatusOK
+	})
+	custom := NewEngine(WithBindErrorHandler(func(ctx *Context, err error) {
+		ctx.StatusCode = http.StatusUnprocessableEntity
+	}))
+	custom.GET("/orders", func(ctx *Context) {
+		var req pageReq
+		_ = ctx.Bind(&req)
+	})
+
+	testCases := []struct {
+		name       string
+		engine     *Engine
+		req        *http.Request
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "json valid",
+			engine:     e,
+			req:        httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"email":"tom@example.com","channel":"web","note":"","items":[{"sku":"abc123","count":2}]}`)),
+			wantStatus: http.StatusOK,
+			wantBody:   `[{"sku":"abc123","count":2}]`,
+		},
+		{
+			name:       "json invalid",
+			engine:     e,
+			req:        httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"email":"tom","channel":"web","note":"","items":[{"sku":"abc123","count":0}]}`)),
+			wantStatus: http.StatusBadRequest,
+			wantBody: `{"message":"invalid request","errors":[` +
+				`{"field":"Email","rule":"email","message":"field Email failed on rule email"},` +
+				`{"field":"Items[0].Count","rule":"gte","param":"1","message":"field Items[0].Count failed on rule gte=1"}]}`,
+		},
+		{
+			name:       "query valid",
+			engine:     e,
+			req:        httptest.NewRequest(http.MethodGet, "/orders?sort=asc", nil),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "query invalid",
+			engine:     e,
+			req:        httptest.NewRequest(http.MethodGet, "/orders?page=0&size=101", nil),
+			wantStatus: http.StatusBadRequest,
+			wantBody: `{"message":"invalid request","errors":[` +
+				`{"field":"Page","rule":"min","param":"1","message":"field Page failed on rule min=1"},` +
+				`{"field":"Size","rule":"max","param":"100","message":"field Size failed on rule max=100"}]}`,
+		},
+		{
+			name:       "bind error",
+			engine:     e,
+			req:        httptest.NewRequest(http.MethodGet, "/orders?page=a", nil),
+			wantStatus: http.StatusBadRequest,
+			wantBody: `{"message":"invalid request","errors":[` +
+				`{"field":"Page","source":"query","message":"bind query \"page\" to field Page: strconv.ParseInt: parsing \"a\": invalid syntax"}]}`,
+		},
+		{
+			name:       "custom handler",
+			engine:     custom,
+			req:        httptest.NewRequest(http.MethodGet, "/orders", nil),
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			tc.engine.ServeHTTP(recorder, tc.req)
+			assert.Equal(t, tc.wantStatus, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package web
+
+import (
+	"github.com/KNICEX/go-web/websocket"
+)
+
+// Upgrade 把当前请求升级为 WebSocket 连接，路由需要注册为 GET，之前执行的 middleware 可以照常鉴权、读取 session
+// 升级成功后响应已经提交，连接需要在处理函数返回前使用完毕并关闭；握手失败时错误响应已经写出
+//
+//	e.GET("/ws", func(ctx *web.Context) {
+//		conn, err := ctx.Upgrade(websocket.WithCompression(true))
+//		if err != nil {
+//			return
+//		}
+//		defer conn.Close()
+//		for {
+//			typ, msg, err := conn.ReadMessage()
+//			if err != nil {
+//				return
+//			}
+//			_ = conn.WriteMessage(typ, msg)
+//		}
+//	})
+func (c *Context) Upgrade(opts ...websocket.Option) (*websocket.Conn, error) {
+	return websocket.Upgrade(c.Writer(), c.Req, opts...)
+}
+
+// IsWebSocket 判断当前请求是否是 WebSocket 握手请求
+func (c *Context) IsWebSocket() bool {
+	return websocket.IsWebSocketUpgrade(c.Req)
+}
@@ -0,0 +1,112 @@
+package websocket
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+	"strings"
+	"sync"
+)
+
+// permessage-deflate 每条消息压缩后末尾的 0x00 0x00 0xff 0xff 不发送，见 RFC 7692 7.2.1
+var deflateTail = []byte{0x00, 0x00, 0xff, 0xff}
+
+var flateWriterPool = sync.Pool{
+	New: func() any {
+		w, _ := flate.NewWriter(nil, flate.BestSpeed)
+		return w
+	},
+}
+
+// compress 压缩一条消息，双方都不保留压缩上下文(no_context_takeover)，每条消息独立压缩
+func compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := flateWriterPool.Get().(*flate.Writer)
+	defer flateWriterPool.Put(w)
+	w.Reset(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), deflateTail), nil
+}
+
+// decompress 解压一条消息，limit 大于 0 时限制解压后的大小
+func decompress(data []byte, limit int64) ([]byte, error) {
+	r := flate.NewReader(io.MultiReader(bytes.NewReader(data), bytes.NewReader(deflateTail)))
+	defer r.Close()
+	var src io.Reader = r
+	if limit > 0 {
+		src = io.LimitReader(r, limit+1)
+	}
+	res, err := io.ReadAll(src)
+	// 末尾补上的是 sync flush 块，不是结束块，读完后会返回 io.ErrUnexpectedEOF
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	if limit > 0 && int64(len(res)) > limit {
+		return nil, ErrReadLimit
+	}
+	return res, nil
+}
+
+// negotiateDeflate 从客户端的 Sec-WebSocket-Extensions 中选择可以接受的 permessage-deflate 参数
+// 服务端总是要求双方不保留压缩上下文，客户端限制服务端窗口大小时不启用压缩
+func negotiateDeflate(header []string) (string, bool) {
+	for _, ext := range parseExtensions(header) {
+		if ext.name != "permessage-deflate" {
+			continue
+		}
+		ok := true
+		for key, val := range ext.params {
+			switch key {
+			case "server_no_context_takeover", "client_no_context_takeover":
+			case "client_max_window_bits":
+				// 服务端解压时不限制窗口大小，可以忽略
+			case "server_max_window_bits":
+				// compress/flate 不能限制窗口大小
+				ok = val == "15"
+			default:
+				ok = false
+			}
+			if !ok {
+				break
+			}
+		}
+		if ok {
+			return "permessage-deflate; server_no_context_takeover; client_no_context_takeover", true
+		}
+	}
+	return "", false
+}
+
+type extension struct {
+	name   string
+	params map[string]string
+}
+
+// parseExtensions 解析 Sec-WebSocket-Extensions，如 permessage-deflate; client_max_window_bits, x-foo
+func parseExtensions(header []string) []extension {
+	var res []extension
+	for _, line := range header {
+		for _, item := range strings.Split(line, ",") {
+			parts := strings.Split(item, ";")
+			name := strings.TrimSpace(parts[0])
+			if name == "" {
+				continue
+			}
+			ext := extension{
+				name:   strings.ToLower(name),
+				params: make(map[string]string, len(parts)-1),
+			}
+			for _, param := range parts[1:] {
+				key, val, _ := strings.Cut(param, "=")
+				ext.params[strings.ToLower(strings.TrimSpace(key))] = strings.Trim(strings.TrimSpace(val), `"`)
+			}
+			res = append(res, ext)
+		}
+	}
+	return res
+}
@@ -0,0 +1,492 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+type MessageType int
+
+const (
+	TextMessage   MessageType = 1
+	BinaryMessage MessageType = 2
+)
+
+// 帧类型
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+// 关闭码，见 RFC 6455 7.4.1
+const (
+	CloseNormalClosure       = 1000
+	CloseGoingAway           = 1001
+	CloseProtocolError       = 1002
+	CloseUnsupportedData     = 1003
+	CloseNoStatusReceived    = 1005
+	CloseAbnormalClosure     = 1006
+	CloseInvalidPayload      = 1007
+	ClosePolicyViolation     = 1008
+	CloseMessageTooBig       = 1009
+	CloseInternalServerError = 1011
+)
+
+const (
+	// 控制帧的最大长度
+	maxControlPayload = 125
+	// NextWriter 每个分片的大小
+	defaultFragmentSize = 4096
+	// CloseWithCode 等待对方关闭帧的时间
+	closeWaitTimeout = time.Second
+)
+
+var (
+	ErrCloseSent = errors.New("websocket: close frame already sent")
+	// ErrReadLimit 消息超过 WithReadLimit 设置的大小
+	ErrReadLimit = errors.New("websocket: message too big")
+)
+
+// CloseError 收到对方的关闭帧，或者因为对方违反协议而关闭连接
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: close %d %s", e.Code, e.Reason)
+}
+
+// Conn 服务端的 WebSocket 连接
+// 同一时刻只能有一个 goroutine 读取，写入可以并发调用
+type Conn struct {
+	conn net.Conn
+	br   *bufio.Reader
+
+	subprotocol string
+	// 是否协商了 permessage-deflate
+	compress bool
+	// 发送消息时是否压缩，只在协商了 permessage-deflate 时生效
+	writeCompress bool
+	readLimit     int64
+	fragmentSize  int
+
+	writeMu   sync.Mutex
+	closeSent bool
+
+	pongHandler func(data []byte)
+}
+
+func newConn(conn net.Conn, br *bufio.Reader, cfg *config, subprotocol string, compress bool) *Conn {
+	return &Conn{
+		conn:          conn,
+		br:            br,
+		subprotocol:   subprotocol,
+		compress:      compress,
+		writeCompress: compress,
+		readLimit:     cfg.readLimit,
+		fragmentSize:  defaultFragmentSize,
+	}
+}
+
+// Subprotocol 返回协商的子协议
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// Compressed 是否协商了 permessage-deflate
+func (c *Conn) Compressed() bool {
+	return c.compress
+}
+
+// EnableWriteCompression 协商了 permessage-deflate 时，设置之后发送的消息是否压缩
+func (c *Conn) EnableWriteCompression(enabled bool) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.writeCompress = enabled
+}
+
+// SetPongHandler 设置收到 pong 时的回调，回调在 ReadMessage 的 goroutine 中执行
+func (c *Conn) SetPongHandler(h func(data []byte)) {
+	c.pongHandler = h
+}
+
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+func (c *Conn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// Close 直接关闭底层连接，不发送关闭帧
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// CloseWithCode 发送关闭帧，等待对方的关闭帧后关闭底层连接
+// 不能和 ReadMessage 并发调用
+func (c *Conn) CloseWithCode(code int, reason string) error {
+	err := c.WriteClose(code, reason)
+	if err == nil {
+		_ = c.conn.SetReadDeadline(time.Now().Add(closeWaitTimeout))
+		for {
+			if _, _, err := c.ReadMessage(); err != nil {
+				break
+			}
+		}
+	}
+	closeErr := c.conn.Close()
+	if err != nil && !errors.Is(err, ErrCloseSent) {
+		return err
+	}
+	return closeErr
+}
+
+// WriteClose 发送关闭帧，之后不能再发送消息
+func (c *Conn) WriteClose(code int, reason string) error {
+	var payload []byte
+	if code != CloseNoStatusReceived {
+		payload = make([]byte, 2, 2+len(reason))
+		binary.BigEndian.PutUint16(payload, uint16(code))
+		payload = append(payload, reason...)
+	}
+	if len(payload) > maxControlPayload {
+		return errors.New("websocket: close reason too long")
+	}
+	return c.writeControl(opClose, payload)
+}
+
+// Ping 发送 ping，对方的 pong 通过 SetPongHandler 接收
+func (c *Conn) Ping(data []byte) error {
+	if len(data) > maxControlPayload {
+		return errors.New("websocket: ping payload too long")
+	}
+	return c.writeControl(opPing, data)
+}
+
+func (c *Conn) writeControl(op byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return ErrCloseSent
+	}
+	if op == opClose {
+		c.closeSent = true
+	}
+	return c.writeFrame(true, false, op, payload)
+}
+
+// WriteMessage 以一个帧发送完整的消息
+func (c *Conn) WriteMessage(typ MessageType, data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return ErrCloseSent
+	}
+	if c.compress && c.writeCompress {
+		compressed, err := compress(data)
+		if err != nil {
+			return err
+		}
+		return c.writeFrame(true, true, byte(typ), compressed)
+	}
+	return c.writeFrame(true, false, byte(typ), data)
+}
+
+// NextWriter 返回发送一条消息的 io.WriteCloser，数据按固定大小分片发送，Close 时发送最后一个分片
+// 压缩的消息在 Close 时整体发送
+// 消息发送完成之前其他 goroutine 的写入会被阻塞
+func (c *Conn) NextWriter(typ MessageType) (io.WriteCloser, error) {
+	c.writeMu.Lock()
+	if c.closeSent {
+		c.writeMu.Unlock()
+		return nil, ErrCloseSent
+	}
+	return &messageWriter{
+		conn:     c,
+		op:       byte(typ),
+		compress: c.compress && c.writeCompress,
+	}, nil
+}
+
+type messageWriter struct {
+	conn     *Conn
+	op       byte
+	compress bool
+	buf      []byte
+	closed   bool
+	// 已经发送过第一个分片，之后的分片为 continuation
+	started bool
+}
+
+func (w *messageWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("websocket: write to closed writer")
+	}
+	w.buf = append(w.buf, p...)
+	if w.compress {
+		return len(p), nil
+	}
+	for len(w.buf) > w.conn.fragmentSize {
+		if err := w.flushFrame(false, w.buf[:w.conn.fragmentSize]); err != nil {
+			return 0, err
+		}
+		w.buf = w.buf[w.conn.fragmentSize:]
+	}
+	return len(p), nil
+}
+
+func (w *messageWriter) flushFrame(fin bool, data []byte) error {
+	op := w.op
+	if w.started {
+		op = opContinuation
+	}
+	w.started = true
+	return w.conn.writeFrame(fin, false, op, data)
+}
+
+func (w *messageWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	defer w.conn.writeMu.Unlock()
+	if w.compress {
+		compressed, err := compress(w.buf)
+		if err != nil {
+			return err
+		}
+		return w.conn.writeFrame(true, true, w.op, compressed)
+	}
+	return w.flushFrame(true, w.buf)
+}
+
+// writeFrame 写入一个帧，服务端发送的帧不使用掩码，调用方需要持有 writeMu
+func (c *Conn) writeFrame(fin bool, rsv1 bool, op byte, payload []byte) error {
+	header := make([]byte, 2, 10)
+	header[0] = op
+	if fin {
+		header[0] |= 0x80
+	}
+	if rsv1 {
+		header[0] |= 0x40
+	}
+	switch l := len(payload); {
+	case l <= 125:
+		header[1] = byte(l)
+	case l <= 0xffff:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(l))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(l))
+	}
+	bufs := net.Buffers{header, payload}
+	_, err := bufs.WriteTo(c.conn)
+	return err
+}
+
+type frame struct {
+	fin     bool
+	rsv1    bool
+	op      byte
+	payload []byte
+}
+
+// ReadMessage 读取下一条完整的消息，自动回复 ping 和关闭帧
+// 收到关闭帧或对方违反协议时返回 *CloseError，此时应该关闭连接
+func (c *Conn) ReadMessage() (MessageType, []byte, error) {
+	var (
+		typ        MessageType
+		data       []byte
+		compressed bool
+		started    bool
+	)
+	for {
+		f, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch f.op {
+		case opPing:
+			if err := c.writeControl(opPong, f.payload); err != nil && !errors.Is(err, ErrCloseSent) {
+				return 0, nil, err
+			}
+			continue
+		case opPong:
+			if c.pongHandler != nil {
+				c.pongHandler(f.payload)
+			}
+			continue
+		case opClose:
+			return 0, nil, c.handleClose(f.payload)
+		case opText, opBinary:
+			if started {
+				return 0, nil, c.fail(CloseProtocolError, "new message before previous one finished")
+			}
+			started = true
+			typ = MessageType(f.op)
+			compressed = f.rsv1
+		case opContinuation:
+			if !started {
+				return 0, nil, c.fail(CloseProtocolError, "unexpected continuation frame")
+			}
+			if f.rsv1 {
+				return 0, nil, c.fail(CloseProtocolError, "rsv1 set on continuation frame")
+			}
+		default:
+			return 0, nil, c.fail(CloseProtocolError, "unknown opcode")
+		}
+
+		if c.readLimit > 0 && int64(len(data)+len(f.payload)) > c.readLimit {
+			return 0, nil, c.fail(CloseMessageTooBig, ErrReadLimit.Error())
+		}
+		data = append(data, f.payload...)
+		if !f.fin {
+			continue
+		}
+
+		if compressed {
+			data, err = decompress(data, c.readLimit)
+			if errors.Is(err, ErrReadLimit) {
+				return 0, nil, c.fail(CloseMessageTooBig, err.Error())
+			}
+			if err != nil {
+				return 0, nil, c.fail(CloseInvalidPayload, "invalid compressed data")
+			}
+		}
+		if typ == TextMessage && !utf8.Valid(data) {
+			return 0, nil, c.fail(CloseInvalidPayload, "invalid utf-8 text")
+		}
+		if data == nil {
+			data = []byte{}
+		}
+		return typ, data, nil
+	}
+}
+
+func (c *Conn) readFrame() (frame, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		return frame{}, err
+	}
+	f := frame{
+		fin:  head[0]&0x80 != 0,
+		rsv1: head[0]&0x40 != 0,
+		op:   head[0] & 0x0f,
+	}
+	if head[0]&0x30 != 0 {
+		return frame{}, c.fail(CloseProtocolError, "rsv2 and rsv3 must be 0")
+	}
+	if f.rsv1 && (!c.compress || f.op >= opClose) {
+		return frame{}, c.fail(CloseProtocolError, "unexpected rsv1")
+	}
+	// 客户端发送的帧必须使用掩码
+	if head[1]&0x80 == 0 {
+		return frame{}, c.fail(CloseProtocolError, "client frame must be masked")
+	}
+
+	length := uint64(head[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return frame{}, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return frame{}, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+		if length > 1<<63-1 {
+			return frame{}, c.fail(CloseProtocolError, "invalid payload length")
+		}
+	}
+
+	if f.op >= opClose {
+		if !f.fin {
+			return frame{}, c.fail(CloseProtocolError, "control frame must not be fragmented")
+		}
+		if length > maxControlPayload {
+			return frame{}, c.fail(CloseProtocolError, "control frame too long")
+		}
+	}
+	if c.readLimit > 0 && length > uint64(c.readLimit) {
+		return frame{}, c.fail(CloseMessageTooBig, ErrReadLimit.Error())
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+		return frame{}, err
+	}
+	f.payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, f.payload); err != nil {
+		return frame{}, err
+	}
+	for i := range f.payload {
+		f.payload[i] ^= mask[i%4]
+	}
+	return f, nil
+}
+
+// handleClose 回复对方的关闭帧
+func (c *Conn) handleClose(payload []byte) error {
+	closeErr := &CloseError{Code: CloseNoStatusReceived}
+	switch {
+	case len(payload) == 1:
+		return c.fail(CloseProtocolError, "invalid close payload")
+	case len(payload) >= 2:
+		closeErr.Code = int(binary.BigEndian.Uint16(payload))
+		closeErr.Reason = string(payload[2:])
+		if !validCloseCode(closeErr.Code) {
+			return c.fail(CloseProtocolError, "invalid close code")
+		}
+		if !utf8.ValidString(closeErr.Reason) {
+			return c.fail(CloseInvalidPayload, "invalid utf-8 close reason")
+		}
+	}
+	// 回复相同的关闭码
+	_ = c.WriteClose(closeErr.Code, "")
+	return closeErr
+}
+
+// fail 因为对方违反协议而发送关闭帧，返回对应的 CloseError
+func (c *Conn) fail(code int, reason string) error {
+	_ = c.WriteClose(code, reason)
+	return &CloseError{Code: code, Reason: reason}
+}
+
+// validCloseCode 判断关闭帧中的关闭码是否合法，1005、1006、1015 等只能在本地使用
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1014:
+		return true
+	case code >= 3000 && code <= 4999:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,337 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testClient 测试用的最简客户端，发送的帧总是使用掩码
+type testClient struct {
+	t    *testing.T
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func dial(t *testing.T, server *httptest.Server, header http.Header) (*testClient, *http.Response) {
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/ws", nil)
+	require.NoError(t, err)
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	for k, vals := range header {
+		req.Header[k] = vals
+	}
+	require.NoError(t, req.Write(conn))
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	require.NoError(t, err)
+	return &testClient{t: t, conn: conn, br: br}, resp
+}
+
+func (c *testClient) writeFrame(fin bool, rsv1 bool, op byte, payload []byte) {
+	head := []byte{op, 0x80}
+	if fin {
+		head[0] |= 0x80
+	}
+	if rsv1 {
+		head[0] |= 0x40
+	}
+	switch l := len(payload); {
+	case l <= 125:
+		head[1] |= byte(l)
+	case l <= 0xffff:
+		head[1] |= 126
+		head = binary.BigEndian.AppendUint16(head, uint16(l))
+	default:
+		head[1] |= 127
+		head = binary.BigEndian.AppendUint64(head, uint64(l))
+	}
+	mask := []byte{1, 2, 3, 4}
+	masked := make([]byte, len(payload))
+	for i := range payload {
+		masked[i] = payload[i] ^ mask[i%4]
+	}
+	_, err := c.conn.Write(append(append(head, mask...), masked...))
+	require.NoError(c.t, err)
+}
+
+// writeUnmasked 发送不使用掩码的帧，用于测试协议错误
+func (c *testClient) writeUnmasked(op byte, payload []byte) {
+	_, err := c.conn.Write(append([]byte{0x80 | op, byte(len(payload))}, payload...))
+	require.NoError(c.t, err)
+}
+
+type testFrame struct {
+	fin     bool
+	rsv1    bool
+	op      byte
+	payload []byte
+}
+
+func (c *testClient) readFrame() testFrame {
+	var head [2]byte
+	_, err := io.ReadFull(c.br, head[:])
+	require.NoError(c.t, err)
+	// 服务端发送的帧不能使用掩码
+	require.Zero(c.t, head[1]&0x80)
+	length := uint64(head[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		_, err = io.ReadFull(c.br, ext[:])
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		_, err = io.ReadFull(c.br, ext[:])
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	require.NoError(c.t, err)
+	payload := make([]byte, length)
+	_, err = io.ReadFull(c.br, payload)
+	require.NoError(c.t, err)
+	return testFrame{
+		fin:     head[0]&0x80 != 0,
+		rsv1:    head[0]&0x40 != 0,
+		op:      head[0] & 0x0f,
+		payload: payload,
+	}
+}
+
+func (c *testClient) readClose() (int, string) {
+	f := c.readFrame()
+	require.Equal(c.t, byte(opClose), f.op)
+	require.GreaterOrEqual(c.t, len(f.payload), 2)
+	return int(binary.BigEndian.Uint16(f.payload)), string(f.payload[2:])
+}
+
+func closePayload(code int, reason string) []byte {
+	return append(binary.BigEndian.AppendUint16(nil, uint16(code)), reason...)
+}
+
+// newEchoServer 原样返回收到的消息，ReadMessage 的错误写入 errs
+func newEchoServer(t *testing.T, opts ...Option) (*httptest.Server, chan error) {
+	errs := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r, opts...)
+		if err != nil {
+			errs <- err
+			return
+		}
+		defer conn.Close()
+		for {
+			typ, msg, err := conn.ReadMessage()
+			if err != nil {
+				errs <- err
+				return
+			}
+			if string(msg) == "fragmented" {
+				w, _ := conn.NextWriter(typ)
+				_, _ = w.Write(bytes.Repeat([]byte("a"), defaultFragmentSize*2+1))
+				_ = w.Close()
+				continue
+			}
+			if err = conn.WriteMessage(typ, msg); err != nil {
+				errs <- err
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, errs
+}
+
+func TestConn_echo(t *testing.T) {
+	server, errs := newEchoServer(t)
+	client, resp := dial(t, server, nil)
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+	client.writeFrame(true, false, opText, []byte("hello"))
+	assert.Equal(t, testFrame{fin: true, op: opText, payload: []byte("hello")}, client.readFrame())
+
+	client.writeFrame(true, false, opBinary, []byte{0, 1, 2})
+	assert.Equal(t, testFrame{fin: true, op: opBinary, payload: []byte{0, 1, 2}}, client.readFrame())
+
+	// 分片的消息中间可以插入控制帧
+	client.writeFrame(false, false, opText, []byte("hel"))
+	client.writeFrame(true, false, opPing, []byte("ping"))
+	client.writeFrame(false, false, opContinuation, []byte("lo "))
+	client.writeFrame(true, false, opContinuation, []byte("world"))
+	assert.Equal(t, testFrame{fin: true, op: opPong, payload: []byte("ping")}, client.readFrame())
+	assert.Equal(t, testFrame{fin: true, op: opText, payload: []byte("hello world")}, client.readFrame())
+
+	// 服务端分片发送
+	client.writeFrame(true, false, opText, []byte("fragmented"))
+	var msg []byte
+	for i := 0; ; i++ {
+		f := client.readFrame()
+		if i == 0 {
+			assert.Equal(t, byte(opText), f.op)
+		} else {
+			assert.Equal(t, byte(opContinuation), f.op)
+		}
+		msg = append(msg, f.payload...)
+		if f.fin {
+			assert.Equal(t, 2, i)
+			break
+		}
+	}
+	assert.Equal(t, defaultFragmentSize*2+1, len(msg))
+
+	client.writeFrame(true, false, opClose, closePayload(CloseGoingAway, "bye"))
+	code, _ := client.readClose()
+	assert.Equal(t, CloseGoingAway, code)
+	var closeErr *CloseError
+	require.ErrorAs(t, <-errs, &closeErr)
+	assert.Equal(t, &CloseError{Code: CloseGoingAway, Reason: "bye"}, closeErr)
+}
+
+func TestConn_protocolError(t *testing.T) {
+	testCases := []struct {
+		name     string
+		opts     []Option
+		send     func(c *testClient)
+		wantCode int
+	}{
+		{
+			name: "unmasked frame",
+			send: func(c *testClient) {
+				c.writeUnmasked(opText, []byte("hello"))
+			},
+			wantCode: CloseProtocolError,
+		},
+		{
+			name: "invalid utf-8",
+			send: func(c *testClient) {
+				c.writeFrame(true, false, opText, []byte{0xff, 0xfe})
+			},
+			wantCode: CloseInvalidPayload,
+		},
+		{
+			name: "message too big",
+			opts: []Option{WithReadLimit(4)},
+			send: func(c *testClient) {
+				c.writeFrame(false, false, opText, []byte("abc"))
+				c.writeFrame(true, false, opContinuation, []byte("de"))
+			},
+			wantCode: CloseMessageTooBig,
+		},
+		{
+			name: "continuation without start",
+			send: func(c *testClient) {
+				c.writeFrame(true, false, opContinuation, []byte("a"))
+			},
+			wantCode: CloseProtocolError,
+		},
+		{
+			name: "fragmented control frame",
+			send: func(c *testClient) {
+				c.writeFrame(false, false, opPing, []byte("a"))
+			},
+			wantCode: CloseProtocolError,
+		},
+		{
+			name: "rsv1 without compression",
+			send: func(c *testClient) {
+				c.writeFrame(true, true, opText, []byte("a"))
+			},
+			wantCode: CloseProtocolError,
+		},
+		{
+			name: "invalid close code",
+			send: func(c *testClient) {
+				c.writeFrame(true, false, opClose, closePayload(1005, ""))
+			},
+			wantCode: CloseProtocolError,
+		},
+		{
+			name: "unknown opcode",
+			send: func(c *testClient) {
+				c.writeFrame(true, false, 0x3, nil)
+			},
+			wantCode: CloseProtocolError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server, errs := newEchoServer(t, tc.opts...)
+			client, _ := dial(t, server, nil)
+			tc.send(client)
+			code, _ := client.readClose()
+			assert.Equal(t, tc.wantCode, code)
+			var closeErr *CloseError
+			require.ErrorAs(t, <-errs, &closeErr)
+			assert.Equal(t, tc.wantCode, closeErr.Code)
+		})
+	}
+}
+
+func TestConn_compression(t *testing.T) {
+	server, _ := newEchoServer(t, WithCompression(true))
+	client, resp := dial(t, server, http.Header{
+		"Sec-Websocket-Extensions": {"permessage-deflate; client_max_window_bits"},
+	})
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	assert.Equal(t, "permessage-deflate; server_no_context_takeover; client_no_context_takeover",
+		resp.Header.Get("Sec-WebSocket-Extensions"))
+
+	msg := strings.Repeat("compress me ", 100)
+	var buf bytes.Buffer
+	fw, _ := flate.NewWriter(&buf, flate.BestCompression)
+	_, _ = fw.Write([]byte(msg))
+	_ = fw.Flush()
+	payload := bytes.TrimSuffix(buf.Bytes(), deflateTail)
+	// 压缩的消息也可以分片
+	client.writeFrame(false, true, opText, payload[:5])
+	client.writeFrame(true, false, opContinuation, payload[5:])
+
+	f := client.readFrame()
+	assert.True(t, f.rsv1)
+	assert.Less(t, len(f.payload), len(msg))
+	data, err := io.ReadAll(flate.NewReader(io.MultiReader(bytes.NewReader(f.payload), bytes.NewReader(deflateTail))))
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		require.NoError(t, err)
+	}
+	assert.Equal(t, msg, string(data))
+}
+
+func TestConn_CloseWithCode(t *testing.T) {
+	done := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			done <- err
+			return
+		}
+		done <- conn.CloseWithCode(ClosePolicyViolation, "unauthorized")
+	}))
+	defer server.Close()
+
+	client, _ := dial(t, server, nil)
+	code, reason := client.readClose()
+	assert.Equal(t, ClosePolicyViolation, code)
+	assert.Equal(t, "unauthorized", reason)
+	client.writeFrame(true, false, opClose, closePayload(code, ""))
+	assert.NoError(t, <-done)
+}
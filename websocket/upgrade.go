@@ -0,0 +1,205 @@
+// Package websocket 实现 RFC 6455 WebSocket 协议的服务端，以及 RFC 7692 permessage-deflate 压缩扩展
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrBadHandshake 请求不是合法的 WebSocket 握手请求，此时已经向客户端返回了错误响应
+var ErrBadHandshake = errors.New("websocket: bad handshake")
+
+// 用于计算 Sec-WebSocket-Accept，见 RFC 6455 1.3
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// 默认的单条消息大小上限
+const defaultReadLimit = 32 << 20
+
+type config struct {
+	checkOrigin      func(r *http.Request) bool
+	subprotocols     []string
+	compression      bool
+	readLimit        int64
+	handshakeTimeout time.Duration
+}
+
+type Option func(c *config)
+
+// WithCheckOrigin 设置 Origin 检查，默认只允许没有 Origin 或者 Origin 和 Host 相同的请求
+func WithCheckOrigin(check func(r *http.Request) bool) Option {
+	return func(c *config) {
+		c.checkOrigin = check
+	}
+}
+
+// WithSubprotocols 设置服务端支持的子协议，按客户端给出的顺序选择第一个服务端支持的
+func WithSubprotocols(protocols ...string) Option {
+	return func(c *config) {
+		c.subprotocols = protocols
+	}
+}
+
+// WithCompression 客户端支持时启用 permessage-deflate 压缩
+func WithCompression(enabled bool) Option {
+	return func(c *config) {
+		c.compression = enabled
+	}
+}
+
+// WithReadLimit 设置单条消息(解压后)的大小上限，默认 32MB，小于等于 0 时不限制
+func WithReadLimit(limit int64) Option {
+	return func(c *config) {
+		c.readLimit = limit
+	}
+}
+
+// WithHandshakeTimeout 设置写入握手响应的超时时间
+func WithHandshakeTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.handshakeTimeout = timeout
+	}
+}
+
+// Upgrade 完成握手并接管连接，w.Header() 中已经设置的响应头(如 Set-Cookie)会随握手响应一起发送
+// 握手失败时向客户端返回错误响应，返回的错误包装了 ErrBadHandshake
+func Upgrade(w http.ResponseWriter, r *http.Request, opts ...Option) (*Conn, error) {
+	cfg := &config{
+		checkOrigin: sameOrigin,
+		readLimit:   defaultReadLimit,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	if r.Method != http.MethodGet {
+		return nil, handshakeError(w, http.StatusMethodNotAllowed, "request method is not GET")
+	}
+	if !headerContainsToken(r.Header, "Connection", "upgrade") {
+		return nil, handshakeError(w, http.StatusBadRequest, "'upgrade' token not found in 'Connection' header")
+	}
+	if !headerContainsToken(r.Header, "Upgrade", "websocket") {
+		return nil, handshakeError(w, http.StatusBadRequest, "'websocket' token not found in 'Upgrade' header")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, handshakeError(w, http.StatusUpgradeRequired, "unsupported version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, handshakeError(w, http.StatusBadRequest, "invalid 'Sec-WebSocket-Key' header")
+	}
+	if !cfg.checkOrigin(r) {
+		return nil, handshakeError(w, http.StatusForbidden, "origin not allowed")
+	}
+
+	subprotocol := selectSubprotocol(r, cfg.subprotocols)
+	var extension string
+	compress := false
+	if cfg.compression {
+		extension, compress = negotiateDeflate(r.Header.Values("Sec-WebSocket-Extensions"))
+	}
+
+	netConn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, handshakeError(w, http.StatusInternalServerError, "can't hijack connection: "+err.Error())
+	}
+	if rw.Reader.Buffered() > 0 {
+		// 客户端不能在收到握手响应之前发送数据
+		_ = netConn.Close()
+		return nil, errors.New("websocket: client sent data before handshake is complete")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: ")
+	sb.WriteString(acceptKey(key))
+	sb.WriteString("\r\n")
+	if subprotocol != "" {
+		sb.WriteString("Sec-WebSocket-Protocol: " + subprotocol + "\r\n")
+	}
+	if extension != "" {
+		sb.WriteString("Sec-WebSocket-Extensions: " + extension + "\r\n")
+	}
+	for k, vals := range w.Header() {
+		if k == "Sec-Websocket-Protocol" || k == "Sec-Websocket-Extensions" {
+			continue
+		}
+		for _, v := range vals {
+			sb.WriteString(k + ": " + strings.NewReplacer("\r", "", "\n", "").Replace(v) + "\r\n")
+		}
+	}
+	sb.WriteString("\r\n")
+
+	if cfg.handshakeTimeout > 0 {
+		_ = netConn.SetWriteDeadline(time.Now().Add(cfg.handshakeTimeout))
+	}
+	if _, err = netConn.Write([]byte(sb.String())); err != nil {
+		_ = netConn.Close()
+		return nil, err
+	}
+	// 清除 http.Server 设置的超时
+	_ = netConn.SetDeadline(time.Time{})
+	return newConn(netConn, bufio.NewReader(netConn), cfg, subprotocol, compress), nil
+}
+
+// IsWebSocketUpgrade 判断请求是否是 WebSocket 握手请求
+func IsWebSocketUpgrade(r *http.Request) bool {
+	return headerContainsToken(r.Header, "Connection", "upgrade") &&
+		headerContainsToken(r.Header, "Upgrade", "websocket")
+}
+
+func handshakeError(w http.ResponseWriter, status int, reason string) error {
+	http.Error(w, http.StatusText(status), status)
+	return fmt.Errorf("%w: %s", ErrBadHandshake, reason)
+}
+
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// sameOrigin 没有 Origin 的请求不是来自浏览器，允许通过
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+func selectSubprotocol(r *http.Request, supported []string) string {
+	for _, line := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, protocol := range strings.Split(line, ",") {
+			protocol = strings.TrimSpace(protocol)
+			for _, s := range supported {
+				if s == protocol {
+					return s
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// headerContainsToken 判断逗号分隔的请求头中是否包含 token，忽略大小写
+func headerContainsToken(header http.Header, name string, token string) bool {
+	for _, line := range header.Values(name) {
+		for _, item := range strings.Split(line, ",") {
+			if strings.EqualFold(strings.TrimSpace(item), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package websocket
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUpgrade_badHandshake(t *testing.T) {
+	validHeader := func() http.Header {
+		return http.Header{
+			"Upgrade":               {"websocket"},
+			"Connection":            {"keep-alive, Upgrade"},
+			"Sec-Websocket-Key":     {"dGhlIHNhbXBsZSBub25jZQ=="},
+			"Sec-Websocket-Version": {"13"},
+		}
+	}
+	testCases := []struct {
+		name       string
+		method     string
+		header     func(h http.Header)
+		wantStatus int
+	}{
+		{
+			name:       "method",
+			method:     http.MethodPost,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name: "no upgrade token",
+			header: func(h http.Header) {
+				h.Set("Connection", "keep-alive")
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "not websocket",
+			header: func(h http.Header) {
+				h.Set("Upgrade", "h2c")
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "version",
+			header: func(h http.Header) {
+				h.Set("Sec-WebSocket-Version", "8")
+			},
+			wantStatus: http.StatusUpgradeRequired,
+		},
+		{
+			name: "invalid key",
+			header: func(h http.Header) {
+				h.Set("Sec-WebSocket-Key", "abc")
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "cross origin",
+			header: func(h http.Header) {
+				h.Set("Origin", "https://evil.example.com")
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "hijack not supported",
+			header: func(h http.Header) {
+				h.Set("Origin", "http://example.com")
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			method := tc.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, "http://example.com/ws", nil)
+			req.Header = validHeader()
+			if tc.header != nil {
+				tc.header(req.Header)
+			}
+			recorder := httptest.NewRecorder()
+			_, err := Upgrade(recorder, req)
+			assert.ErrorIs(t, err, ErrBadHandshake)
+			assert.Equal(t, tc.wantStatus, recorder.Code)
+			if tc.wantStatus == http.StatusUpgradeRequired {
+				assert.Equal(t, "13", recorder.Header().Get("Sec-WebSocket-Version"))
+			}
+		})
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	subprotocol := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "sid=1")
+		conn, err := Upgrade(w, r, WithSubprotocols("v2.chat", "v1.chat"), WithCompression(true))
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		subprotocol <- conn.Subprotocol()
+	}))
+	defer server.Close()
+
+	_, resp := dial(t, server, http.Header{
+		"Sec-Websocket-Protocol": {"v1.chat, v2.chat"},
+		// 不能限制服务端的窗口大小，忽略压缩
+		"Sec-Websocket-Extensions": {"permessage-deflate; server_max_window_bits=10"},
+	})
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	// RFC 6455 1.3 中的示例
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", resp.Header.Get("Sec-WebSocket-Accept"))
+	assert.Equal(t, "v1.chat", resp.Header.Get("Sec-WebSocket-Protocol"))
+	assert.Empty(t, resp.Header.Get("Sec-WebSocket-Extensions"))
+	assert.Equal(t, "sid=1", resp.Header.Get("Set-Cookie"))
+	assert.Equal(t, "v1.chat", <-subprotocol)
+}
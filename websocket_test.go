@@ -0,0 +1,106 @@
+package web
+
+import (
+	"bufio"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestContext_Upgrade(t *testing.T) {
+	e := NewEngine()
+	e.Use(func(ctx *Context) {
+		if ctx.Req.URL.Query().Get("token") != "secret" {
+			ctx.StatusCode = http.StatusUnauthorized
+			ctx.Abort()
+			return
+		}
+		ctx.Resp.Header().Set("X-User", "tom")
+		ctx.Next()
+		// 连接已经被接管，不会再输出
+		ctx.RespData = []byte("after upgrade")
+	})
+	e.GET("/ws", func(ctx *Context) {
+		if !ctx.IsWebSocket() {
+			ctx.StatusCode = http.StatusBadRequest
+			return
+		}
+		conn, err := ctx.Upgrade()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		typ, msg, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		_ = conn.WriteMessage(typ, append([]byte("echo: "), msg...))
+		_, _, _ = conn.ReadMessage()
+	})
+	server := httptest.NewServer(e)
+	defer server.Close()
+	// 挂载到其他 Engine 下
+	parent := NewEngine()
+	parent.Mount("/child", e)
+	mounted := httptest.NewServer(parent)
+	defer mounted.Close()
+
+	handshake := func(t *testing.T, server *httptest.Server, path string) (net.Conn, *bufio.Reader, *http.Response) {
+		conn, err := net.Dial("tcp", server.Listener.Addr().String())
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			_ = conn.Close()
+		})
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+		req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		require.NoError(t, err)
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		req.Header.Set("Sec-WebSocket-Version", "13")
+		require.NoError(t, req.Write(conn))
+		br := bufio.NewReader(conn)
+		resp, err := http.ReadResponse(br, req)
+		require.NoError(t, err)
+		return conn, br, resp
+	}
+
+	t.Run("unauthorized", func(t *testing.T) {
+		_, _, resp := handshake(t, server, "/ws")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	echo := func(t *testing.T, server *httptest.Server, path string) {
+		conn, br, resp := handshake(t, server, path)
+		require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+		assert.Equal(t, "tom", resp.Header.Get("X-User"))
+
+		// 带掩码的文本帧 "hi"
+		mask := []byte{1, 2, 3, 4}
+		frame := []byte{0x81, 0x80 | 2, mask[0], mask[1], mask[2], mask[3], 'h' ^ mask[0], 'i' ^ mask[1]}
+		_, err := conn.Write(frame)
+		require.NoError(t, err)
+
+		head := make([]byte, 2)
+		_, err = io.ReadFull(br, head)
+		require.NoError(t, err)
+		assert.Equal(t, byte(0x81), head[0])
+		payload := make([]byte, head[1])
+		_, err = io.ReadFull(br, payload)
+		require.NoError(t, err)
+		assert.Equal(t, "echo: hi", string(payload))
+	}
+
+	t.Run("echo", func(t *testing.T) {
+		echo(t, server, "/ws?token=secret")
+	})
+
+	t.Run("mounted", func(t *testing.T) {
+		echo(t, mounted, "/child/ws?token=secret")
+	})
+}
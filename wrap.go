@@ -0,0 +1,135 @@
+package web
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// mountParam Mount 注册的 *name 通配符的参数名，值为去掉前缀后的路径
+const mountParam = "mountpath"
+
+// anyMethods Mount 注册路由时使用的全部http method
+var anyMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodOptions,
+	http.MethodConnect,
+	http.MethodTrace,
+}
+
+// WrapH 把 http.Handler 转换为 HandleFunc，请求路径保持不变
+// h 写入的状态码和响应体保存到 Context.StatusCode 和 Context.RespData，和其他 HandleFunc 一样由 Engine 统一输出
+// h 调用 Flush 后改为直接写入客户端，也可以接管连接处理 WebSocket 等
+func WrapH(h http.Handler) HandleFunc {
+	return func(ctx *Context) {
+		serveHandler(ctx, h, ctx.Req)
+	}
+}
+
+// WrapF 把 http.HandlerFunc 转换为 HandleFunc
+func WrapF(f http.HandlerFunc) HandleFunc {
+	return WrapH(f)
+}
+
+// mountHandler 去掉路由前缀后把请求交给 h 处理，请求路径为 / 加上 *mountpath 匹配到的部分
+// 非严格模式下 *mountpath 不包含末尾的 /，按原始请求路径补上，否则 http.FileServer 等会重定向到同一个地址
+func mountHandler(h http.Handler) HandleFunc {
+	return func(ctx *Context) {
+		rest, _ := ctx.PathParams.Get(mountParam)
+		p := "/" + rest
+		if !strings.HasSuffix(p, "/") && strings.HasSuffix(ctx.Req.URL.Path, "/") {
+			p += "/"
+		}
+		req := new(http.Request)
+		*req = *ctx.Req
+		req.URL = new(url.URL)
+		*req.URL = *ctx.Req.URL
+		req.URL.Path = p
+		req.URL.RawPath = ""
+		serveHandler(ctx, h, req)
+	}
+}
+
+func serveHandler(ctx *Context, h http.Handler, req *http.Request) {
+	w := &bufferedWriter{ctx: ctx}
+	h.ServeHTTP(w, req)
+	if !w.wroteHeader {
+		// 和 net/http 一致，没有写入任何内容时响应 200
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+var (
+	_ http.Flusher  = &bufferedWriter{}
+	_ http.Hijacker = &bufferedWriter{}
+)
+
+// bufferedWriter 把 http.Handler 的响应写入 Context，响应头直接写入 Context.Resp
+// Flush 之后不再缓存，直接写入 Context.Writer
+type bufferedWriter struct {
+	ctx         *Context
+	wroteHeader bool
+	streaming   bool
+}
+
+func (w *bufferedWriter) Header() http.Header {
+	return w.ctx.Resp.Header()
+}
+
+func (w *bufferedWriter) WriteHeader(status int) {
+	if w.wroteHeader || status == 0 {
+		return
+	}
+	w.wroteHeader = true
+	w.ctx.StatusCode = status
+}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.streaming {
+		return w.ctx.Writer().Write(data)
+	}
+	w.ctx.RespData = append(w.ctx.RespData, data...)
+	return len(data), nil
+}
+
+func (w *bufferedWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	rw := w.ctx.Writer()
+	if !w.streaming {
+		w.streaming = true
+		data := w.ctx.RespData
+		w.ctx.RespData = nil
+		rw.WriteHeader(w.ctx.StatusCode)
+		if len(data) > 0 && w.ctx.Req.Method != http.MethodHead {
+			_, _ = rw.Write(data)
+		}
+	}
+	rw.Flush()
+}
+
+// Hijack 接管底层连接，已经缓存的响应被丢弃
+func (w *bufferedWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := w.ctx.writer.Hijack()
+	if err == nil {
+		w.wroteHeader = true
+		w.ctx.RespData = nil
+	}
+	return conn, rw, err
+}
+
+// Unwrap 用于 http.ResponseController 访问 Context.Writer，如设置读写超时
+func (w *bufferedWriter) Unwrap() http.ResponseWriter {
+	return w.ctx.Writer()
+}